@@ -1,38 +1,66 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"os"
-
-	"p1/pkg/assembler"
-	"p1/pkg/assembler/lexer"
-)
-
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Uso: go run cmd/assembler/main.go <arquivo.asm> (exemplo: io/asm/output.asm)")
-	}
-
-	asmFile := os.Args[1]
-
-	tokens := lexer.GetTokens(asmFile)
-
-	asmb := assembler.NewAssembler(tokens)
-
-	// Primeira passagem: coleta rótulos e define o PC.
-	if err := asmb.FirstPass(); err != nil {
-		log.Fatalf("Erro na primeira passagem: %v", err)
-	}
-
-	// Segunda passagem: gera o código final, resolvendo operandos.
-	if err := asmb.SecondPass(); err != nil {
-		log.Fatalf("Erro na segunda passagem: %v", err)
-	}
-
-	if err := asmb.WriteMEM("io/build/output.mem"); err != nil {
-		log.Fatalf("Erro ao escrever o arquivo .mem: %v", err)
-	}
-
-	fmt.Println("Arquivo .mem gerado com sucesso na pasta io/build/output.mem")
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"p1/pkg/assembler"
+	"p1/pkg/diag"
+)
+
+func main() {
+	ahmes := flag.Bool("ahmes", false, "habilita o conjunto de instruções do Ahmes")
+	lst := flag.String("lst", "", "grava também a listagem (.lst) neste arquivo")
+	sym := flag.String("sym", "", "grava também a tabela de símbolos (.sym) neste arquivo")
+	reloca := flag.Bool("reloca", false, "põe os dados logo depois do código, ignorando os ORG do .DATA")
+	formato := flag.String("formato", "mem", "formato de saída: "+strings.Join(assembler.NomesFormatos(), ", "))
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("Uso: go run cmd/assembler/main.go [-ahmes] [-reloca] [-lst arquivo.lst] [-sym arquivo.sym] [-formato mem|hex|bin|logisim|verilog] <arquivo.asm> (exemplo: io/asm/output.asm)")
+	}
+
+	extensao, ok := assembler.Formatos[*formato]
+	if !ok {
+		log.Fatalf("Formato desconhecido: %s (use %s)", *formato, strings.Join(assembler.NomesFormatos(), ", "))
+	}
+	saida := "io/build/output" + extensao
+
+	asmFile := flag.Arg(0)
+
+	fonte, err := os.ReadFile(asmFile)
+	if err != nil {
+		log.Fatalf("Não foi possível ler o arquivo: %v", err)
+	}
+
+	asmb, err := assembler.Assemble(string(fonte), *ahmes, *reloca)
+	if err != nil {
+		fmt.Fprint(os.Stderr, diag.Format(asmFile, string(fonte), err))
+		os.Exit(1)
+	}
+
+	if err := asmb.WriteFormat(saida, *formato); err != nil {
+		log.Fatalf("Erro ao escrever o arquivo %s: %v", extensao, err)
+	}
+
+	fmt.Printf("Arquivo %s gerado com sucesso na pasta %s\n", extensao, saida)
+
+	if *lst != "" {
+		if err := asmb.WriteLST(*lst, string(fonte)); err != nil {
+			log.Fatalf("Erro ao escrever o arquivo .lst: %v", err)
+		}
+		fmt.Printf("Listagem gerada em %s\n", *lst)
+	}
+
+	if *sym != "" {
+		tabela := asmb.TabelaSimbolos(string(fonte))
+		tabela.Fonte = asmFile
+		if err := tabela.Grava(*sym); err != nil {
+			log.Fatalf("Erro ao escrever o arquivo .sym: %v", err)
+		}
+		fmt.Printf("Tabela de símbolos gerada em %s\n", *sym)
+	}
+}
@@ -1,43 +1,47 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 
-	"p1/pkg/compiler/generator"
-	"p1/pkg/compiler/lexer"
-	"p1/pkg/compiler/parser"
+	"p1/pkg/compiler"
+	"p1/pkg/diag"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Uso: go run cmd/compiler/main.go <arquivo.lfh> (exemplo: io/linguagemCriada/program.ldh)")
+	otimiza := flag.Bool("O", false, "otimiza o assembly gerado")
+	mem := flag.Bool("mem", false, "mostra o uso de memória do programa")
+	layout := flag.Bool("emit-layout", false, "mostra o endereço de cada variável, constante e temporário")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("Uso: go run cmd/compiler/main.go [-O] [-mem] [-emit-layout] <arquivo.lfh> (exemplo: io/linguagemCriada/program.ldh)")
 	}
 
-	inputFile := os.Args[1]
+	inputFile := flag.Arg(0)
 	conteudo, err := os.ReadFile(inputFile)
 	if err != nil {
 		log.Fatalf("Erro ao ler o arquivo: %v", err)
 	}
 
-	tokens, err := lexer.Lex(string(conteudo))
-	if err != nil {
-		log.Fatalf("Erro léxico: %v", err)
+	prog, avisos, err := compiler.CompileASM(string(conteudo), *otimiza)
+	if *mem && len(prog.Code) > 0 {
+		fmt.Print(prog.Memoria())
 	}
-
-	parser := parser.NewParser(tokens)
-	instrucoes, err := parser.ParsePrograma()
 	if err != nil {
-		log.Fatalf("Erro de parsing: %v", err)
+		fmt.Fprint(os.Stderr, diag.Format(inputFile, string(conteudo), diag.Junta(avisos.Err(), err)))
+		os.Exit(1)
+	}
+	if len(avisos) > 0 {
+		fmt.Fprint(os.Stderr, diag.Format(inputFile, string(conteudo), avisos))
+	}
+	if *layout {
+		fmt.Print(prog.Layout())
 	}
 
-	prog := generator.GenerateASM(instrucoes)
-
-	output := strings.Join(append(prog.Code, prog.Data...), "\n")
-
-	err = os.WriteFile("io/asm/output.asm", []byte(output), 0644)
+	err = os.WriteFile("io/asm/output.asm", []byte(prog.String()), 0644)
 	if err != nil {
 		log.Fatalf("Erro ao salvar arquivo .asm: %v", err)
 	}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"p1/pkg/assembler"
+	"p1/pkg/assembler/lexer"
+	"p1/pkg/debugger"
+	"p1/pkg/encoder"
+	"p1/pkg/simbolos"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("Uso: go run cmd/debugger/main.go <arquivo.mem> [arquivo.asm|arquivo.sym] (exemplo: io/build/output.mem io/asm/output.asm)")
+	}
+
+	imagem, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatalf("Não foi possível ler o arquivo: %v", err)
+	}
+
+	cpu := encoder.NewCPU()
+	if err := cpu.Load(imagem); err != nil {
+		log.Fatalf("Não foi possível carregar o arquivo: %v", err)
+	}
+
+	// O .asm (ou o .sym gerado pelo assembler) é opcional e serve apenas
+	// para conhecer os rótulos.
+	var labels map[string]uint8
+	switch {
+	case len(os.Args) >= 3 && strings.HasSuffix(os.Args[2], ".sym"):
+		tabela, err := simbolos.Le(os.Args[2])
+		if err != nil {
+			log.Fatal(err)
+		}
+		labels = tabela.Labels()
+	case len(os.Args) >= 3:
+		tokens, err := lexer.GetTokens(os.Args[2], cpu.Ahmes)
+		if err != nil {
+			log.Fatalf("Não foi possível ler o arquivo .asm: %v", err)
+		}
+		asmb := assembler.NewAssembler(tokens)
+		if err := asmb.FirstPass(); err != nil {
+			log.Fatalf("Erro na primeira passagem: %v", err)
+		}
+		labels = asmb.Labels
+	}
+
+	debugger.NewDebugger(cpu, labels, os.Stdout).Run(os.Stdin)
+}
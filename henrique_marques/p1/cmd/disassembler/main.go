@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"p1/pkg/disassembler"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("Uso: go run cmd/disassembler/main.go <arquivo.mem> [saida.asm] (exemplo: io/build/output.mem io/asm/desmontado.asm)")
+	}
+
+	imagem, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatalf("Não foi possível ler o arquivo: %v", err)
+	}
+
+	d, err := disassembler.NewDisassembler(imagem)
+	if err != nil {
+		log.Fatalf("Não foi possível carregar o arquivo: %v", err)
+	}
+	asm := d.Disassemble()
+
+	// Sem arquivo de saída, o .asm vai para a saída padrão.
+	if len(os.Args) < 3 {
+		fmt.Print(asm)
+		return
+	}
+	if err := os.WriteFile(os.Args[2], []byte(asm), 0644); err != nil {
+		log.Fatalf("Erro ao escrever o arquivo .asm: %v", err)
+	}
+	fmt.Printf("Arquivo .asm gerado com sucesso em %s\n", os.Args[2])
+}
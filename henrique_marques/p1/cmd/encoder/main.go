@@ -1,16 +1,48 @@
-package main
-
-import ( 
-	"log"
-	"os"
-	"p1/pkg/encoder"
-) 
-
-func main() { 
-	if len(os.Args) < 2 { 
-		log.Fatal("Uso: go run cmd/encoder/main.go <arquivo.mem> (exemplo: io/build/output.mem)") 
-	}
-
-	memFile := os.Args[1]
-	encoder.RunBinary(memFile)
-}
\ No newline at end of file
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"p1/pkg/encoder"
+	"p1/pkg/simbolos"
+)
+
+func main() {
+	ahmes := flag.Bool("ahmes", false, "executa no modo Ahmes mesmo com cabeçalho do Neander")
+	sym := flag.String("sym", "", "tabela de símbolos (padrão: o .sym com o mesmo nome do .mem, se existir)")
+	maxPassos := flag.Int("max-passos", 0, "interrompe após este número de instruções (0: sem limite)")
+	tempo := flag.Duration("tempo", 10*time.Second, "interrompe após este tempo de execução (0: sem limite)")
+	laco := flag.Bool("laco", true, "interrompe quando a máquina repete um estado (laço infinito)")
+	es := flag.String("es", "numeros", "dispositivo de E/S nos endereços FE/FF: "+encoder.ModosES)
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("Uso: go run cmd/encoder/main.go [-ahmes] [-sym arquivo.sym] [-max-passos n] [-tempo 10s] [-laco=false] [-es modo] <arquivo.mem> (exemplo: io/build/output.mem)")
+	}
+
+	memFile := flag.Arg(0)
+
+	// Com a tabela de símbolos, a memória final é mostrada por variável.
+	var tabela *simbolos.Tabela
+	var err error
+	if *sym != "" {
+		tabela, err = simbolos.Le(*sym)
+	} else {
+		tabela, err = simbolos.Procura(memFile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dispositivo, err := encoder.DeviceByName(*es, os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opcoes := encoder.RunOptions{MaxSteps: *maxPassos, Timeout: *tempo, DetectLoops: *laco, Device: dispositivo}
+	if err := encoder.RunBinary(memFile, *ahmes, tabela, opcoes); err != nil {
+		log.Fatal(err)
+	}
+}
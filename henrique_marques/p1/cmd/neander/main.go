@@ -0,0 +1,425 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"p1/pkg/assembler"
+	"p1/pkg/compiler"
+	"p1/pkg/diag"
+	"p1/pkg/disassembler"
+	"p1/pkg/encoder"
+	"p1/pkg/golden"
+	"p1/pkg/simbolos"
+)
+
+// Códigos de saída.
+const (
+	saidaOK   = 0
+	saidaErro = 1 // erro no programa de entrada ou ao ler/gravar arquivos
+	saidaUso  = 2 // comando ou opções inválidos
+)
+
+const uso = `Uso: neander <comando> [opções] [arquivo]
+
+Comandos:
+  compile   compila um .ldh para .asm
+  assemble  monta um .asm em .mem
+  build     compila e monta um .ldh direto para .mem
+  run       executa um .mem no emulador
+  disasm    desmonta um .mem para .asm
+  test      executa programas .ldh/.asm e confere as anotações "; expect"
+
+Sem arquivo, ou com "-", a entrada é lida da entrada padrão. Com "-o -" a
+saída vai para a saída padrão. Use "neander <comando> -h" para ver as opções
+de cada comando.
+`
+
+// erroUso é um erro nas opções de um comando. Sem mensagem, o erro já foi
+// explicado pelo FlagSet.
+type erroUso struct {
+	msg string
+}
+
+func (e *erroUso) Error() string { return e.msg }
+
+// cli guarda as entradas e saídas padrão, para que os comandos não
+// dependam de os.Stdin/os.Stdout diretamente.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.executa(os.Args[1:]))
+}
+
+func (c *cli) executa(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(c.stderr, uso)
+		return saidaUso
+	}
+
+	var err error
+	switch args[0] {
+	case "compile":
+		err = c.compile(args[1:])
+	case "assemble":
+		err = c.assemble(args[1:])
+	case "build":
+		err = c.build(args[1:])
+	case "run":
+		err = c.run(args[1:])
+	case "disasm":
+		err = c.disasm(args[1:])
+	case "test":
+		err = c.test(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(c.stdout, uso)
+		return saidaOK
+	default:
+		fmt.Fprintf(c.stderr, "neander: comando desconhecido: %s\n\n%s", args[0], uso)
+		return saidaUso
+	}
+
+	var errUso *erroUso
+	var fonte *erroFonte
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return saidaOK
+	case errors.As(err, &errUso):
+		if errUso.msg != "" {
+			fmt.Fprintf(c.stderr, "neander: %s\n", errUso.msg)
+		}
+		return saidaUso
+	case errors.As(err, &fonte):
+		fmt.Fprint(c.stderr, diag.Format(fonte.arquivo, fonte.texto, fonte.err))
+	default:
+		fmt.Fprintf(c.stderr, "neander: %v\n", err)
+	}
+	return saidaErro
+}
+
+// erroFonte são erros de compilação ou montagem, escritos no formato do
+// GCC junto com o código-fonte.
+type erroFonte struct {
+	arquivo string
+	texto   string
+	err     error
+}
+
+func (e *erroFonte) Error() string { return e.err.Error() }
+
+// flags cria o FlagSet de um comando. O FlagSet escreve a ajuda e os erros
+// de opção em stderr.
+func (c *cli) flags(nome, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(nome, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Uso: neander %s [opções] %s\n", nome, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse lê as opções e devolve o único argumento posicional (a entrada),
+// ou "-" se não houver nenhum.
+func parse(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", err
+		}
+		return "", &erroUso{}
+	}
+	switch fs.NArg() {
+	case 0:
+		return "-", nil
+	case 1:
+		return fs.Arg(0), nil
+	}
+	fs.Usage()
+	return "", &erroUso{}
+}
+
+func (c *cli) compile(args []string) error {
+	fs := c.flags("compile", "[arquivo.ldh]")
+	saida := fs.String("o", "", "arquivo .asm de saída (padrão: nome da entrada com .asm)")
+	otimiza := fs.Bool("O", false, "otimiza o assembly gerado")
+	mem := fs.Bool("mem", false, "mostra o uso de memória do programa em stderr")
+	layout := fs.Bool("emit-layout", false, "mostra em stderr o endereço de cada variável, constante e temporário")
+	entrada, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	nome, fonte, err := c.le(entrada)
+	if err != nil {
+		return err
+	}
+	asm, err := c.compila(nome, string(fonte), *otimiza, *mem, *layout)
+	if err != nil {
+		return err
+	}
+	return c.grava(destino(*saida, entrada, ".asm"), []byte(asm+"\n"))
+}
+
+func (c *cli) assemble(args []string) error {
+	fs := c.flags("assemble", "[arquivo.asm]")
+	saida := fs.String("o", "", "arquivo de saída (padrão: nome da entrada com a extensão do formato)")
+	formato := fs.String("formato", "mem", "formato de saída: "+strings.Join(assembler.NomesFormatos(), ", "))
+	ahmes := fs.Bool("ahmes", false, "habilita o conjunto de instruções do Ahmes")
+	lst := fs.String("lst", "", "grava também a listagem (.lst) neste arquivo")
+	sym := fs.String("sym", "", "grava também a tabela de símbolos (.sym) neste arquivo")
+	reloca := fs.Bool("reloca", false, "põe os dados logo depois do código, ignorando os ORG do .DATA")
+	entrada, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	extensao, err := extensaoDo(*formato)
+	if err != nil {
+		return err
+	}
+
+	nome, fonte, err := c.le(entrada)
+	if err != nil {
+		return err
+	}
+	asmb, err := assembler.Assemble(string(fonte), *ahmes, *reloca)
+	if err != nil {
+		return &erroFonte{nome, string(fonte), err}
+	}
+	if *lst != "" {
+		if err := c.grava(*lst, []byte(asmb.Listing(string(fonte)))); err != nil {
+			return err
+		}
+	}
+	if *sym != "" {
+		tabela := asmb.TabelaSimbolos(string(fonte))
+		tabela.Fonte = nome
+		if err := c.gravaSimbolos(*sym, tabela); err != nil {
+			return err
+		}
+	}
+	return c.gravaImagem(asmb, destino(*saida, entrada, extensao), *formato)
+}
+
+func (c *cli) build(args []string) error {
+	fs := c.flags("build", "[arquivo.ldh]")
+	saida := fs.String("o", "", "arquivo de saída (padrão: nome da entrada com a extensão do formato)")
+	formato := fs.String("formato", "mem", "formato de saída: "+strings.Join(assembler.NomesFormatos(), ", "))
+	asmSaida := fs.String("asm", "", "grava também o assembly gerado neste arquivo")
+	sym := fs.String("sym", "", "grava também a tabela de símbolos (.sym) neste arquivo")
+	otimiza := fs.Bool("O", false, "otimiza o assembly gerado")
+	mem := fs.Bool("mem", false, "mostra o uso de memória do programa em stderr")
+	layout := fs.Bool("emit-layout", false, "mostra em stderr o endereço de cada variável, constante e temporário")
+	entrada, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	extensao, err := extensaoDo(*formato)
+	if err != nil {
+		return err
+	}
+
+	nome, fonte, err := c.le(entrada)
+	if err != nil {
+		return err
+	}
+	asm, err := c.compila(nome, string(fonte), *otimiza, *mem, *layout)
+	if err != nil {
+		return err
+	}
+	if *asmSaida != "" {
+		if err := c.grava(*asmSaida, []byte(asm+"\n")); err != nil {
+			return err
+		}
+	}
+	asmb, err := assembler.Assemble(asm, false, false)
+	if err != nil {
+		// O assembly gerado pelo compilador deveria sempre montar.
+		return &erroFonte{"<assembly gerado>", asm, err}
+	}
+	if *sym != "" {
+		tabela := asmb.TabelaSimbolos(asm)
+		tabela.Fonte = *asmSaida
+		tabela.Origem = nome
+		if err := c.gravaSimbolos(*sym, tabela); err != nil {
+			return err
+		}
+	}
+	return c.gravaImagem(asmb, destino(*saida, entrada, extensao), *formato)
+}
+
+func (c *cli) run(args []string) error {
+	fs := c.flags("run", "[arquivo.mem]")
+	ahmes := fs.Bool("ahmes", false, "executa no modo Ahmes mesmo com cabeçalho do Neander")
+	sym := fs.String("sym", "", "tabela de símbolos (padrão: o .sym com o mesmo nome do .mem, se existir)")
+	maxPassos := fs.Int("max-passos", 0, "interrompe após este número de instruções (0: sem limite)")
+	tempo := fs.Duration("tempo", 10*time.Second, "interrompe após este tempo de execução (0: sem limite)")
+	laco := fs.Bool("laco", true, "interrompe quando a máquina repete um estado (laço infinito)")
+	es := fs.String("es", "numeros", "dispositivo de E/S nos endereços FE/FF: "+encoder.ModosES)
+	entrada, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	_, imagem, err := c.le(entrada)
+	if err != nil {
+		return err
+	}
+	// Com a tabela de símbolos, a memória final é mostrada por variável.
+	var tabela *simbolos.Tabela
+	switch {
+	case *sym != "":
+		tabela, err = simbolos.Le(*sym)
+	case entrada != "-":
+		tabela, err = simbolos.Procura(entrada)
+	}
+	if err != nil {
+		return err
+	}
+	dispositivo, err := encoder.DeviceByName(*es, c.stdin, c.stdout)
+	if err != nil {
+		return err
+	}
+	opcoes := encoder.RunOptions{MaxSteps: *maxPassos, Timeout: *tempo, DetectLoops: *laco, Device: dispositivo}
+	return encoder.RunImage(imagem, *ahmes, tabela, opcoes, c.stdout)
+}
+
+func (c *cli) disasm(args []string) error {
+	fs := c.flags("disasm", "[arquivo.mem]")
+	saida := fs.String("o", "-", "arquivo .asm de saída")
+	entrada, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	_, imagem, err := c.le(entrada)
+	if err != nil {
+		return err
+	}
+	d, err := disassembler.NewDisassembler(imagem)
+	if err != nil {
+		return err
+	}
+	return c.grava(*saida, []byte(d.Disassemble()))
+}
+
+func (c *cli) test(args []string) error {
+	fs := c.flags("test", "arquivo.ldh|arquivo.asm...")
+	otimiza := fs.Bool("O", false, "compila os programas .ldh com otimização")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &erroUso{}
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &erroUso{}
+	}
+
+	falhas := 0
+	for _, arquivo := range fs.Args() {
+		r, err := golden.VerificaArquivo(arquivo, *otimiza)
+		switch {
+		case err != nil:
+			fmt.Fprintf(c.stdout, "FALHA %s\n%v\n", arquivo, err)
+			falhas++
+		case !r.Ok():
+			fmt.Fprintf(c.stdout, "FALHA %s\n", arquivo)
+			for _, f := range r.Falhas {
+				fmt.Fprintf(c.stdout, "  %s\n", f)
+			}
+			falhas++
+		default:
+			fmt.Fprintf(c.stdout, "ok    %s (%d expectativas)\n", arquivo, len(r.Expectativas))
+		}
+	}
+	if falhas > 0 {
+		return fmt.Errorf("%d de %d programas falharam", falhas, fs.NArg())
+	}
+	return nil
+}
+
+// compila traduz o .ldh para assembly. Com mem, escreve em stderr o uso de
+// memória, mesmo quando o programa não cabe.
+func (c *cli) compila(nome, fonte string, otimiza, mem, layout bool) (string, error) {
+	prog, avisos, err := compiler.CompileASM(fonte, otimiza)
+	if mem && len(prog.Code) > 0 {
+		fmt.Fprint(c.stderr, prog.Memoria())
+	}
+	if err != nil {
+		return "", &erroFonte{nome, fonte, diag.Junta(avisos.Err(), err)}
+	}
+	if len(avisos) > 0 {
+		fmt.Fprint(c.stderr, diag.Format(nome, fonte, avisos))
+	}
+	if layout {
+		fmt.Fprint(c.stderr, prog.Layout())
+	}
+	return prog.String(), nil
+}
+
+func extensaoDo(formato string) (string, error) {
+	extensao, ok := assembler.Formatos[formato]
+	if !ok {
+		return "", &erroUso{fmt.Sprintf("formato desconhecido: %s (use %s)", formato, strings.Join(assembler.NomesFormatos(), ", "))}
+	}
+	return extensao, nil
+}
+
+// le lê o arquivo de entrada, ou a entrada padrão se caminho for "-", e
+// devolve também o nome usado nas mensagens de erro.
+func (c *cli) le(caminho string) (string, []byte, error) {
+	if caminho == "-" {
+		dados, err := io.ReadAll(c.stdin)
+		return "<stdin>", dados, err
+	}
+	dados, err := os.ReadFile(caminho)
+	return caminho, dados, err
+}
+
+// grava escreve dados no arquivo, ou na saída padrão se caminho for "-".
+func (c *cli) grava(caminho string, dados []byte) error {
+	if caminho == "-" {
+		_, err := c.stdout.Write(dados)
+		return err
+	}
+	return os.WriteFile(caminho, dados, 0644)
+}
+
+func (c *cli) gravaSimbolos(caminho string, tabela *simbolos.Tabela) error {
+	if caminho == "-" {
+		return tabela.Escreve(c.stdout)
+	}
+	return tabela.Grava(caminho)
+}
+
+func (c *cli) gravaImagem(asmb *assembler.Assembler, caminho, formato string) error {
+	if caminho == "-" {
+		return asmb.EscreveFormato(c.stdout, formato)
+	}
+	return asmb.WriteFormat(caminho, formato)
+}
+
+// destino escolhe o arquivo de saída: o pedido com -o ou, se não houver, o
+// nome da entrada com a extensão trocada. Com entrada padrão, a saída
+// padrão.
+func destino(saida, entrada, extensao string) string {
+	if saida != "" {
+		return saida
+	}
+	if entrada == "-" {
+		return "-"
+	}
+	return strings.TrimSuffix(entrada, filepath.Ext(entrada)) + extensao
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const soma = "PROGRAMA \"SOMA\"\nINICIO\nLEIA A\nLEIA B\nESCREVA A + B\nFIM\n"
+
+// roda executa o neander com os argumentos e a entrada padrão dados.
+func roda(stdin string, args ...string) (codigo int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}
+	codigo = c.executa(args)
+	return codigo, out.String(), errOut.String()
+}
+
+func TestComandos(t *testing.T) {
+	casos := []struct {
+		nome   string
+		stdin  string
+		args   []string
+		codigo int
+		stdout string
+		stderr string
+	}{
+		{nome: "sem comando", codigo: saidaUso, stderr: "Uso: neander <comando>"},
+		{nome: "ajuda", args: []string{"help"}, codigo: saidaOK, stdout: "Comandos:"},
+		{nome: "ajuda de um comando", args: []string{"run", "-h"}, codigo: saidaOK, stderr: "-max-passos"},
+		{nome: "comando desconhecido", args: []string{"executa"}, codigo: saidaUso, stderr: "comando desconhecido: executa"},
+		{nome: "opção desconhecida", args: []string{"compile", "-x"}, codigo: saidaUso, stderr: "flag provided but not defined: -x"},
+		{nome: "duas entradas", args: []string{"compile", "a.ldh", "b.ldh"}, codigo: saidaUso, stderr: "Uso: neander compile"},
+		{nome: "formato desconhecido", args: []string{"build", "-formato", "ihex"}, codigo: saidaUso, stderr: "formato desconhecido: ihex"},
+		{nome: "compile da entrada padrão", stdin: soma, args: []string{"compile"}, codigo: saidaOK, stdout: ".CODE"},
+		{nome: "erro de compilação", stdin: "PROGRAMA \"T\"\nINICIO\nA = 1 +\nFIM\n", args: []string{"compile"}, codigo: saidaErro, stderr: "<stdin>:3:8: error: Esperada expressão\nA = 1 +\n       ^\n"},
+		{nome: "aviso não impede a compilação", stdin: "PROGRAMA \"T\"\nVARIAVEIS A, X\nINICIO\nA = 1\nFIM\n", args: []string{"compile", "-o", "-"}, codigo: saidaOK, stdout: ".CODE", stderr: "<stdin>:2:14: warning: variável X declarada e não usada"},
+		{nome: "assemble em Intel HEX", stdin: ".CODE\nHLT\n", args: []string{"assemble", "-formato", "hex"}, codigo: saidaOK, stdout: ":10000000F0"},
+		{nome: "erro de montagem", stdin: ".CODE\nLDA -1\n", args: []string{"assemble"}, codigo: saidaErro, stderr: "<stdin>:2:5: error: token desconhecido: -1"},
+		{nome: "build para a saída padrão", stdin: soma, args: []string{"build", "-formato", "logisim"}, codigo: saidaOK, stdout: "v2.0 raw\n"},
+		{nome: "arquivo inexistente", args: []string{"run", "nada.mem"}, codigo: saidaErro, stderr: "neander: "},
+		{nome: "modo de E/S desconhecido", stdin: "\x03NDR", args: []string{"run", "-es", "texto"}, codigo: saidaErro, stderr: "modo de E/S desconhecido: texto"},
+		{nome: "test sem arquivos", args: []string{"test"}, codigo: saidaUso, stderr: "Uso: neander test"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			codigo, stdout, stderr := roda(c.stdin, c.args...)
+			if codigo != c.codigo {
+				t.Errorf("código %d, esperado %d\nstdout:\n%s\nstderr:\n%s", codigo, c.codigo, stdout, stderr)
+			}
+			if !strings.Contains(stdout, c.stdout) {
+				t.Errorf("stdout sem %q:\n%s", c.stdout, stdout)
+			}
+			if !strings.Contains(stderr, c.stderr) {
+				t.Errorf("stderr sem %q:\n%s", c.stderr, stderr)
+			}
+		})
+	}
+}
+
+// build grava o .mem e o .sym ao lado do .ldh; run os encontra pelo nome,
+// lê a entrada padrão e mostra o valor final das variáveis.
+func TestBuildERun(t *testing.T) {
+	dir := t.TempDir()
+	fonte := filepath.Join(dir, "soma.ldh")
+	if err := os.WriteFile(fonte, []byte(soma), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imagem := filepath.Join(dir, "soma.mem")
+	if codigo, _, stderr := roda("", "build", "-O", "-sym", filepath.Join(dir, "soma.sym"), fonte); codigo != saidaOK {
+		t.Fatalf("build: código %d\n%s", codigo, stderr)
+	}
+	if info, err := os.Stat(imagem); err != nil || info.Size() != 516 {
+		t.Fatalf("imagem: %v", err)
+	}
+
+	codigo, stdout, stderr := roda("3 4", "run", imagem)
+	if codigo != saidaOK {
+		t.Fatalf("run: código %d\n%s", codigo, stderr)
+	}
+	for _, trecho := range []string{"7\n", "Parada em HLT", "========== Variáveis ===========", "A                [", "= 03 (3)"} {
+		if !strings.Contains(stdout, trecho) {
+			t.Errorf("saída sem %q:\n%s", trecho, stdout)
+		}
+	}
+
+	// Sem entrada suficiente, run falha depois do relatório.
+	codigo, stdout, stderr = roda("3", "run", imagem)
+	if codigo != saidaErro || !strings.Contains(stderr, "entrada esgotada") || !strings.Contains(stdout, "Interrompida") {
+		t.Errorf("run sem entrada: código %d\nstdout:\n%s\nstderr:\n%s", codigo, stdout, stderr)
+	}
+
+	// disasm devolve um assembly que monta na mesma imagem.
+	asm := filepath.Join(dir, "soma.asm")
+	if codigo, _, stderr := roda("", "disasm", "-o", asm, imagem); codigo != saidaOK {
+		t.Fatalf("disasm: código %d\n%s", codigo, stderr)
+	}
+	if codigo, _, stderr := roda("", "assemble", "-o", filepath.Join(dir, "volta.mem"), asm); codigo != saidaOK {
+		t.Fatalf("assemble: código %d\n%s", codigo, stderr)
+	}
+	original, _ := os.ReadFile(imagem)
+	volta, _ := os.ReadFile(filepath.Join(dir, "volta.mem"))
+	if !bytes.Equal(original, volta) {
+		t.Error("a imagem remontada difere da original")
+	}
+}
+
+func TestTest(t *testing.T) {
+	codigo, stdout, _ := roda("", "test", "../../pkg/golden/testdata/soma.ldh", "../../pkg/golden/testdata/laco.asm")
+	if codigo != saidaOK || strings.Count(stdout, "ok    ") != 2 {
+		t.Errorf("código %d:\n%s", codigo, stdout)
+	}
+
+	dir := t.TempDir()
+	errado := filepath.Join(dir, "errado.ldh")
+	if err := os.WriteFile(errado, []byte("PROGRAMA \"T\"\nINICIO\nA = 2\nFIM\n; expect A = 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	codigo, stdout, stderr := roda("", "test", errado)
+	if codigo != saidaErro || !strings.Contains(stdout, "FALHA "+errado) || !strings.Contains(stderr, "1 de 1 programas falharam") {
+		t.Errorf("código %d\nstdout:\n%s\nstderr:\n%s", codigo, stdout, stderr)
+	}
+}
@@ -1,33 +1,33 @@
-.CODE
-ORG 00
-LDA CONST_3
-ADD CONST_4
-STA TMP0
-LDA CONST_2
-NOT
-ADD CONST_01
-STA TMP2
-LDA TMP0
-ADD TMP2
-STA TMP1
-LDA TMP1
-STA A
-LDA A
-ADD A
-ADD A
-STA TMP3
-LDA TMP3
-STA Y
-HLT
-.DATA
-ORG 20
-CONST_3 DB 3
-CONST_4 DB 4
-TMP0 DB 00
-CONST_2 DB 2
-TMP1 DB 00
-TMP2 DB 00
-CONST_01 DB 01
-TMP3 DB 00
-A DB 00
-Y DB 00
\ No newline at end of file
+.CODE
+ORG 00
+; @linha 3
+LDA CONST_03
+ADD CONST_04
+STA TMP0
+LDA CONST_02
+NOT
+ADD CONST_01
+STA TMP1
+LDA TMP0
+ADD TMP1
+STA TMP0
+LDA TMP0
+STA A
+; @linha 4
+LDA A
+ADD A
+ADD A
+STA TMP0
+LDA TMP0
+STA Y
+; @linha 5
+HLT
+.DATA
+CONST_03 DB 03
+CONST_04 DB 04
+CONST_02 DB 02
+CONST_01 DB 01
+A DB 00
+Y DB 00
+TMP0 DB 00 ; 3 + 4, (3 + 4) - 2, A * 3
+TMP1 DB 00 ; -2
\ No newline at end of file
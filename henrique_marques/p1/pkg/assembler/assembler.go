@@ -1,229 +1,506 @@
-package assembler
-
-import (
-	"fmt"
-	"os"
-	"strconv"
-	"strings"
-
-	"p1/pkg/assembler/lexer"
-)
-
-const (
-	TOKEN_SECTION = "SECTION"
-	TOKEN_EOF     = "EOF"
-	TOKEN_INSTR   = "INSTRUCTION"
-	TOKEN_NUMBER  = "NUMBER"
-	TOKEN_VAR     = "VARIABLE"
-	TOKEN_DEFINE  = "DEFINE"
-	TOKEN_UNKNOWN = "UNKNOWN"
-)
-
-var (
-	Instructions = map[string]uint8{
-		"NOP": 0x00, "STA": 0x10, "LDA": 0x20, "ADD": 0x30,
-		"OR": 0x40, "AND": 0x50, "NOT": 0x60, "JMP": 0x80,
-		"JN": 0x90, "JZ": 0xA0, "HLT": 0xF0,
-	}
-)
-
-type Assembler struct {
-	Tokens  []lexer.Token
-	PC      uint8         
-	Output  []uint8
-	Labels  map[string]uint8
-	StartPC uint8
-}
-
-func NewAssembler(tokens []lexer.Token) *Assembler {
-	return &Assembler{
-		Tokens:  tokens,
-		PC:      0,
-		Labels:  make(map[string]uint8),
-		StartPC: 0,
-	}
-}
-
-// FirstPass calcula os endereços dos rótulos e atualiza o PC conforme as diretivas.
-// Nesta passagem, o PC é incrementado de forma contínua, respeitando a ordem das seções.
-func (a *Assembler) FirstPass() error {
-	currentSection := "CODE"
-	for i := 0; i < len(a.Tokens); i++ {
-		token := a.Tokens[i]
-		if token.Tipo == TOKEN_SECTION {
-			currentSection = strings.ToUpper(token.Valor)
-			continue
-		}
-		if currentSection == "CODE" {
-			switch token.Tipo {
-			case TOKEN_INSTR, TOKEN_NUMBER, TOKEN_VAR:
-				a.PC += 2
-			case TOKEN_DEFINE:
-				if token.Valor == "ORG" {
-					i++
-					if i >= len(a.Tokens) {
-						return fmt.Errorf("esperado número após ORG")
-					}
-					value, err := parseNumber(a.Tokens[i].Valor)
-					if err != nil {
-						return fmt.Errorf("número inválido após ORG: %s", a.Tokens[i].Valor)
-					}
-					a.PC = uint8(value)
-					a.StartPC = uint8(value)
-				}
-			}
-		} else if currentSection == "DATA" {
-			if token.Tipo == TOKEN_DEFINE && token.Valor == "ORG" {
-				i++
-				if i >= len(a.Tokens) {
-					return fmt.Errorf("esperado número após ORG")
-				}
-				value, err := parseNumber(a.Tokens[i].Valor)
-				if err != nil {
-					return fmt.Errorf("número inválido após ORG: %s", a.Tokens[i].Valor)
-				}
-				a.PC = uint8(value)
-				continue
-			}
-
-			if token.Tipo == TOKEN_VAR {
-				a.Labels[token.Valor] = a.PC
-				continue
-			}
-
-			if token.Tipo == TOKEN_DEFINE && token.Valor == "DB" {
-				i++
-				if i >= len(a.Tokens) {
-					return fmt.Errorf("esperado número após DB")
-				}
-				a.PC += 2
-			}
-		}
-	}
-	return nil
-}
-
-// SecondPass gera o buffer de memória (512 bytes) com base nos tokens.
-// Na seção CODE, escreve as instruções de forma sequencial (usando um PC local).
-// Na seção DATA, escreve os valores nos endereços definidos pelos rótulos.
-func (a *Assembler) SecondPass() error {
-	mem := make([]uint8, 512)
-	pcCode := a.StartPC
-	currentSection := "CODE"
-	var currentVar string
-
-	for i := 0; i < len(a.Tokens); i++ {
-		token := a.Tokens[i]
-		if token.Tipo == TOKEN_SECTION {
-			currentSection = strings.ToUpper(token.Valor)
-			continue
-		}
-
-		if currentSection == "CODE" {
-			switch token.Tipo {
-			case TOKEN_INSTR:
-				opcode, ok := Instructions[token.Valor]
-				if !ok {
-					return fmt.Errorf("instrução desconhecida: %s", token.Valor)
-				}
-				realAddr := pcCode * 2
-				mem[realAddr] = opcode
-				mem[realAddr+1] = 0x00
-				pcCode += 1
-			case TOKEN_NUMBER:
-				value, err := parseNumber(token.Valor)
-				if err != nil {
-					return fmt.Errorf("número inválido: %s", token.Valor)
-				}
-				realAddr := pcCode * 2
-				mem[realAddr] = uint8(value)
-				mem[realAddr+1] = 0x00
-				pcCode += 1
-			case TOKEN_VAR:
-				addr, ok := a.Labels[token.Valor]
-				if !ok {
-					return fmt.Errorf("label não definida: %s", token.Valor)
-				}
-				realAddr := pcCode * 2
-				mem[realAddr] = addr
-				mem[realAddr+1] = 0x00
-				pcCode += 1
-			case TOKEN_DEFINE:
-				if token.Valor == "ORG" {
-					i++
-					if i >= len(a.Tokens) {
-						return fmt.Errorf("esperado número após ORG")
-					}
-					value, err := parseNumber(a.Tokens[i].Valor)
-					if err != nil {
-						return fmt.Errorf("número inválido após ORG: %s", a.Tokens[i].Valor)
-					}
-					pcCode = uint8(value)
-				}
-			}
-		} else if currentSection == "DATA" {
-			if token.Tipo == TOKEN_VAR {
-				currentVar = token.Valor
-			} else if token.Tipo == TOKEN_DEFINE {
-				if token.Valor == "DB" {
-					i++
-					if i >= len(a.Tokens) {
-						return fmt.Errorf("esperado número após DB")
-					}
-					value, err := parseNumber(a.Tokens[i].Valor)
-					if err != nil {
-						return fmt.Errorf("número inválido após DB: %s", a.Tokens[i].Valor)
-					}
-					addr, ok := a.Labels[currentVar]
-					if !ok {
-						return fmt.Errorf("label não definida para variável: %s", currentVar)
-					}
-					realAddr := addr * 2
-					mem[realAddr] = uint8(value)
-					mem[realAddr+1] = 0x00
-				} else if token.Valor == "ORG" {
-					i++
-					if i >= len(a.Tokens) {
-						return fmt.Errorf("esperado número após ORG")
-					}
-					value, err := parseNumber(a.Tokens[i].Valor)
-					if err != nil {
-						return fmt.Errorf("número inválido após ORG: %s", a.Tokens[i].Valor)
-					}
-					_ = value
-				}
-			}
-		}
-	}
-
-	a.Output = mem
-	return nil
-}
-
-
-func parseNumber(s string) (uint64, error) {
-	return strconv.ParseUint(s, 16, 8)
-}
-
-// WriteMEM grava o arquivo .mem com um cabeçalho fixo (4 bytes) e preenche até 516 bytes.
-func (a *Assembler) WriteMEM(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	header := []uint8{0x03, 0x4E, 0x44, 0x52} // Cabeçalho fixo
-	output := append(header, a.Output...)
-
-	for len(output) < 516 {
-		output = append(output, 0x00)
-	}
-
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"p1/pkg/assembler/lexer"
+	"p1/pkg/diag"
+)
+
+const (
+	TOKEN_SECTION = "SECTION"
+	TOKEN_EOF     = "EOF"
+	TOKEN_INSTR   = "INSTRUCTION"
+	TOKEN_NUMBER  = "NUMBER"
+	TOKEN_VAR     = "VARIABLE"
+	TOKEN_DEFINE  = "DEFINE"
+	TOKEN_UNKNOWN = "UNKNOWN"
+	TOKEN_STRING  = "STRING"
+	TOKEN_COMMA   = "COMMA"
+	TOKEN_LABEL   = "LABEL"
+	TOKEN_EXPR    = "EXPRESSION"
+)
+
+// tamanhoMemoria é o número de palavras de memória do Neander.
+const tamanhoMemoria = 256
+
+var (
+	Instructions = map[string]uint8{
+		"NOP": 0x00, "STA": 0x10, "LDA": 0x20, "ADD": 0x30,
+		"OR": 0x40, "AND": 0x50, "NOT": 0x60, "JMP": 0x80,
+		"JN": 0x90, "JZ": 0xA0, "HLT": 0xF0,
+	}
+
+	AhmesInstructions = map[string]uint8{
+		"SUB": 0x70, "JP": 0x94, "JV": 0x98, "JNV": 0x9C,
+		"JNZ": 0xA4, "JC": 0xB0, "JNC": 0xB4, "JB": 0xB8,
+		"JNB": 0xBC, "SHR": 0xE0, "SHL": 0xE1, "ROR": 0xE2,
+		"ROL": 0xE3,
+	}
+
+	// SemOperando lista as instruções que ocupam uma única palavra; as
+	// demais são seguidas por uma palavra de endereço.
+	SemOperando = map[string]bool{
+		"NOP": true, "NOT": true, "HLT": true,
+		"SHR": true, "SHL": true, "ROR": true, "ROL": true,
+	}
+)
+
+type Assembler struct {
+	Tokens []lexer.Token
+	PC     uint8
+	Output []uint8
+	Labels map[string]uint8
+	// Constantes guarda os símbolos definidos com EQU.
+	Constantes map[string]uint8
+	// Ahmes habilita o conjunto de instruções do Ahmes. Também é ligado
+	// pela diretiva .AHMES no código-fonte.
+	Ahmes bool
+	// RelocaDados põe as seções .DATA logo depois de todo o código,
+	// ignorando os ORG delas.
+	RelocaDados bool
+
+	// emitidas guarda, por linha do código-fonte, as palavras gravadas
+	// pela SecondPass; é usado na listagem e na tabela de símbolos.
+	emitidas map[int][]palavra
+	// rotulos guarda onde cada rótulo foi definido e se ele marca dados;
+	// é preenchido pela FirstPass e usado na tabela de símbolos.
+	rotulos map[string]rotulo
+	// ocupadas guarda a linha que ocupou cada endereço na SecondPass (0
+	// para livre), para detectar sobreposições; estourou evita repetir o
+	// erro de memória excedida.
+	ocupadas [tamanhoMemoria]int
+	estourou bool
+}
+
+func NewAssembler(tokens []lexer.Token) *Assembler {
+	return &Assembler{
+		Tokens:     tokens,
+		PC:         0,
+		Labels:     make(map[string]uint8),
+		Constantes: make(map[string]uint8),
+		rotulos:    make(map[string]rotulo),
+	}
+}
+
+// Assemble monta o código-fonte .asm, rodando as duas passagens. Os erros de
+// ambas são devolvidos juntos; o Assembler é devolvido mesmo com erros. Com
+// relocaDados, os dados vão para depois do código (veja RelocaDados).
+func Assemble(fonte string, ahmes, relocaDados bool) (*Assembler, error) {
+	tokenize := lexer.Tokenize
+	if ahmes {
+		tokenize = lexer.TokenizeAhmes
+	}
+	tokens, errLex := tokenize(strings.NewReader(fonte))
+	a := NewAssembler(tokens)
+	a.Ahmes = ahmes
+	a.RelocaDados = relocaDados
+
+	// A segunda passagem roda mesmo com erros na primeira para que todos
+	// sejam reportados juntos.
+	errPrimeira := a.FirstPass()
+	errSegunda := a.SecondPass()
+	return a, diag.Junta(errLex, errPrimeira, errSegunda)
+}
+
+// FirstPass expande as macros, calcula os endereços dos rótulos e o valor
+// das constantes (EQU) e atualiza o PC conforme as instruções e diretivas.
+// Nesta passagem, o PC é incrementado de forma contínua, respeitando a ordem das seções.
+// Cada token do .CODE ocupa uma palavra de memória; DB ocupa uma palavra por
+// valor (uma por caractere nas strings) e DS n reserva n palavras.
+// Os erros são acumulados e retornados juntos em uma diag.Lista.
+func (a *Assembler) FirstPass() error {
+	var erros diag.Lista
+	a.Tokens = expandeMacros(a.Tokens, &erros)
+	if a.RelocaDados {
+		a.Tokens = realocaDados(a.Tokens)
+	}
+	a.PC = 0
+	currentSection := "CODE"
+	for i := 0; i < len(a.Tokens); i++ {
+		token := a.Tokens[i]
+		switch {
+		case token.Tipo == TOKEN_UNKNOWN:
+			// Já reportado pelo lexer.
+		case token.Tipo == TOKEN_SECTION:
+			if strings.ToUpper(token.Valor) == "AHMES" {
+				a.Ahmes = true
+				continue
+			}
+			currentSection = strings.ToUpper(token.Valor)
+		case token.Tipo == TOKEN_COMMA:
+			erros.Add(token.Pos, "vírgula inesperada")
+		case token.Tipo == TOKEN_STRING:
+			erros.Add(token.Pos, "string fora de DB")
+		case a.isDiretiva(i+1, "EQU") && a.isLabelDef(i):
+			i += 2
+			value, ok := a.operandoDiretiva(i, "EQU", &erros)
+			if !ok {
+				continue
+			}
+			if a.redefinido(token, &erros) {
+				continue
+			}
+			a.Constantes[token.Valor] = uint8(value)
+		case a.isDiretiva(i, "ORG"):
+			i++
+			value, ok := a.operandoDiretiva(i, "ORG", &erros)
+			if !ok {
+				continue
+			}
+			a.PC = uint8(value)
+		case a.isDiretiva(i, "DB"):
+			var valores []lexer.Token
+			valores, i = a.valoresDB(i, &erros)
+			for _, v := range valores {
+				if v.Tipo == TOKEN_STRING {
+					a.PC += uint8(len([]rune(v.Valor)))
+				} else {
+					a.PC++
+				}
+			}
+		case a.isDiretiva(i, "DS"):
+			i++
+			value, ok := a.operandoDiretiva(i, "DS", &erros)
+			if !ok {
+				continue
+			}
+			a.PC += uint8(value)
+		case token.Tipo == TOKEN_LABEL || a.isRotulo(i, currentSection):
+			if !a.redefinido(token, &erros) {
+				a.Labels[token.Valor] = a.PC
+				a.rotulos[token.Valor] = rotulo{
+					linha: token.Pos.Linha,
+					dado:  currentSection == "DATA" || a.isDiretiva(i+1, "DB") || a.isDiretiva(i+1, "DS"),
+				}
+			}
+		case currentSection == "CODE":
+			switch token.Tipo {
+			case TOKEN_INSTR, TOKEN_NUMBER, TOKEN_VAR, TOKEN_EXPR:
+				a.PC++
+			}
+		}
+	}
+	return erros.Err()
+}
+
+// SecondPass gera o buffer de memória (512 bytes) com base nos tokens.
+// O PC segue exatamente o mesmo caminho da FirstPass: no .CODE cada
+// instrução ou operando é gravado na posição atual; DB grava seus valores a
+// partir dela e DS apenas avança o PC. Palavras gravadas ou reservadas
+// além da última posição de memória, ou em uma posição já ocupada, são
+// erros.
+func (a *Assembler) SecondPass() error {
+	var erros diag.Lista
+	mem := make([]uint8, 512)
+	a.emitidas = map[int][]palavra{}
+	a.ocupadas = [tamanhoMemoria]int{}
+	a.estourou = false
+	pc := 0
+	currentSection := "CODE"
+
+	for i := 0; i < len(a.Tokens); i++ {
+		token := a.Tokens[i]
+		switch {
+		case token.Tipo == TOKEN_SECTION:
+			if strings.ToUpper(token.Valor) == "AHMES" {
+				continue
+			}
+			currentSection = strings.ToUpper(token.Valor)
+		case a.isDiretiva(i+1, "EQU") && a.isLabelDef(i):
+			i += 2
+		case a.isDiretiva(i, "ORG"):
+			i++
+			value, ok := a.operandoDiretiva(i, "ORG", nil)
+			if !ok {
+				continue
+			}
+			pc = int(value)
+		case a.isDiretiva(i, "DB"):
+			var valores []lexer.Token
+			valores, i = a.valoresDB(i, nil)
+			for _, v := range valores {
+				if v.Tipo == TOKEN_STRING {
+					for _, c := range v.Valor {
+						if c > 0xFF {
+							erros.Add(v.Pos, "caractere fora da faixa de 8 bits: %q", c)
+						}
+						a.grava(mem, pc, uint8(c), v.Pos, &erros)
+						pc++
+					}
+					continue
+				}
+				a.grava(mem, pc, a.valor(v, &erros), v.Pos, &erros)
+				pc++
+			}
+		case a.isDiretiva(i, "DS"):
+			i++
+			value, ok := a.operandoDiretiva(i, "DS", nil)
+			if !ok {
+				continue
+			}
+			for n := 0; n < int(value); n++ {
+				if !a.ocupa(pc+n, a.Tokens[i-1].Pos, &erros) {
+					break
+				}
+			}
+			pc += int(value)
+		case token.Tipo == TOKEN_LABEL || a.isRotulo(i, currentSection):
+		case currentSection == "CODE":
+			switch token.Tipo {
+			case TOKEN_INSTR:
+				opcode, err := a.opcode(token.Valor)
+				if err != nil {
+					erros.Add(token.Pos, "%v", err)
+				}
+				a.grava(mem, pc, opcode, token.Pos, &erros)
+				pc++
+			case TOKEN_NUMBER, TOKEN_VAR, TOKEN_EXPR:
+				a.grava(mem, pc, a.valor(token, &erros), token.Pos, &erros)
+				pc++
+			}
+		}
+	}
+
+	a.Output = mem
+	return erros.Err()
+}
+
+// valor resolve um operando (veja avalia) e registra o erro na posição do
+// token se ele não puder ser resolvido.
+func (a *Assembler) valor(token lexer.Token, erros *diag.Lista) uint8 {
+	if _, ahmes := AhmesInstructions[token.Valor]; ahmes && token.Tipo == TOKEN_VAR {
+		if _, ok := a.simbolo(token.Valor); !ok {
+			erros.Add(token.Pos, "instrução %s disponível apenas no modo AHMES", token.Valor)
+			return 0
+		}
+	}
+	value, err := a.avalia(token.Valor)
+	if err != nil {
+		erros.Add(token.Pos, "%v", err)
+	}
+	return value
+}
+
+// avalia calcula o valor de um operando: um rótulo, uma constante EQU, um
+// número hexadecimal ou uma soma/subtração deles, como TABELA+1. Símbolos
+// têm prioridade porque nomes como "A" ou "CAFE" também são números
+// válidos. O resultado é truncado para 8 bits.
+func (a *Assembler) avalia(expr string) (uint8, error) {
+	original := expr
+	var total uint8
+	sinal := uint8(1)
+	for expr != "" {
+		fim := strings.IndexAny(expr, "+-")
+		if fim < 0 {
+			fim = len(expr)
+		}
+		termo := expr[:fim]
+		if termo == "" {
+			return 0, fmt.Errorf("termo vazio na expressão: %s", original)
+		}
+		value, ok := a.simbolo(termo)
+		if !ok {
+			n, err := parseNumber(termo)
+			switch {
+			case err == nil:
+				value = uint8(n)
+			case termo[0] == '_' || unicode.IsLetter(rune(termo[0])):
+				return 0, fmt.Errorf("label não definida: %s", termo)
+			default:
+				return 0, fmt.Errorf("número inválido: %s", termo)
+			}
+		}
+		total += sinal * value
+		if fim == len(expr) {
+			break
+		}
+		if expr[fim] == '-' {
+			sinal = 0xFF
+		} else {
+			sinal = 1
+		}
+		expr = expr[fim+1:]
+		if expr == "" {
+			return 0, fmt.Errorf("termo vazio na expressão: %s", original)
+		}
+	}
+	return total, nil
+}
+
+// redefinido informa, registrando o erro, se o rótulo ou constante definido
+// pelo token já existe.
+func (a *Assembler) redefinido(token lexer.Token, erros *diag.Lista) bool {
+	if _, existe := a.simbolo(token.Valor); existe {
+		erros.Add(token.Pos, "símbolo já definido: %s", token.Valor)
+		return true
+	}
+	return false
+}
+
+func (a *Assembler) simbolo(nome string) (uint8, bool) {
+	if addr, ok := a.Labels[nome]; ok {
+		return addr, true
+	}
+	value, ok := a.Constantes[nome]
+	return value, ok
+}
+
+// valoresDB lê a lista de valores separados por vírgula que segue o DB no
+// token i e devolve os valores e o índice do último token consumido. Cada
+// valor deve estar na mesma linha do DB ou da vírgula que o precede.
+func (a *Assembler) valoresDB(i int, erros *diag.Lista) ([]lexer.Token, int) {
+	var valores []lexer.Token
+	for {
+		i++
+		if i >= len(a.Tokens) || !isValorDB(a.Tokens[i]) || a.Tokens[i].Pos.Linha != a.Tokens[i-1].Pos.Linha {
+			if erros != nil {
+				erros.Add(a.Tokens[i-1].Pos, "esperado valor após %s", a.Tokens[i-1].Valor)
+			}
+			return valores, i - 1
+		}
+		valores = append(valores, a.Tokens[i])
+		if i+1 >= len(a.Tokens) || a.Tokens[i+1].Tipo != TOKEN_COMMA {
+			return valores, i
+		}
+		i++
+	}
+}
+
+func isValorDB(token lexer.Token) bool {
+	switch token.Tipo {
+	case TOKEN_NUMBER, TOKEN_VAR, TOKEN_EXPR, TOKEN_STRING:
+		return true
+	}
+	return false
+}
+
+// grava escreve a palavra no endereço addr do buffer (2 bytes por palavra)
+// e registra de qual linha do código-fonte ela veio. Se o endereço não
+// puder ser ocupado (veja ocupa), nada é gravado.
+func (a *Assembler) grava(mem []uint8, addr int, valor uint8, pos diag.Pos, erros *diag.Lista) {
+	if !a.ocupa(addr, pos, erros) {
+		return
+	}
+	realAddr := addr * 2
+	mem[realAddr] = valor
+	mem[realAddr+1] = 0x00
+	a.emitidas[pos.Linha] = append(a.emitidas[pos.Linha], palavra{addr: uint8(addr), valor: valor})
+}
+
+// ocupa marca o endereço addr como usado pela linha de pos. Um endereço
+// além da memória ou já ocupado é registrado como erro e devolve falso; o
+// estouro da memória é reportado uma única vez.
+func (a *Assembler) ocupa(addr int, pos diag.Pos, erros *diag.Lista) bool {
+	if addr >= tamanhoMemoria {
+		if !a.estourou {
+			erros.Add(pos, "o programa excede a memória: endereço %X depois da última posição (FF)", addr)
+			a.estourou = true
+		}
+		return false
+	}
+	if linha := a.ocupadas[addr]; linha != 0 {
+		erros.Add(pos, "sobreposição no endereço %02X, já ocupado pela linha %d", addr, linha)
+		return false
+	}
+	a.ocupadas[addr] = pos.Linha
+	return true
+}
+
+// realocaDados move o conteúdo das seções .DATA, sem os ORG, para depois de
+// todos os tokens de código, de modo que os dados comecem logo após a
+// última palavra do código.
+func realocaDados(tokens []lexer.Token) []lexer.Token {
+	var codigo, dados []lexer.Token
+	secao := "CODE"
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.Tipo == TOKEN_EOF:
+			dados = append(dados, token)
+			continue
+		case token.Tipo == TOKEN_SECTION && strings.ToUpper(token.Valor) != "AHMES":
+			secao = strings.ToUpper(token.Valor)
+		}
+		if secao != "DATA" || token.Tipo == TOKEN_SECTION && strings.ToUpper(token.Valor) == "AHMES" {
+			codigo = append(codigo, token)
+			continue
+		}
+		if token.Tipo == TOKEN_DEFINE && token.Valor == "ORG" {
+			i++
+			continue
+		}
+		dados = append(dados, token)
+	}
+	return append(codigo, dados...)
+}
+
+// operandoDiretiva lê o valor que acompanha ORG, DS ou EQU no token i. Ele
+// pode usar apenas símbolos definidos antes da diretiva. Os erros são
+// registrados em erros, se não for nil, para que não se repitam nas duas
+// passagens.
+func (a *Assembler) operandoDiretiva(i int, diretiva string, erros *diag.Lista) (uint64, bool) {
+	if i >= len(a.Tokens) || a.Tokens[i].Tipo == TOKEN_EOF {
+		if erros != nil {
+			erros.Add(a.Tokens[i-1].Pos, "esperado número após %s", diretiva)
+		}
+		return 0, false
+	}
+	value, err := a.avalia(a.Tokens[i].Valor)
+	if err != nil {
+		if erros != nil {
+			erros.Add(a.Tokens[i].Pos, "valor inválido após %s: %v", diretiva, err)
+		}
+		return 0, false
+	}
+	return uint64(value), true
+}
+
+// isLabelDef informa se o token i define um rótulo de dados ou uma
+// constante. Nomes como "A" ou "CAFE" também são números hexadecimais
+// válidos, então um NUMBER seguido de DB, DS ou EQU é tratado como rótulo.
+func (a *Assembler) isLabelDef(i int) bool {
+	switch a.Tokens[i].Tipo {
+	case TOKEN_VAR:
+		return true
+	case TOKEN_NUMBER:
+		return a.isDiretiva(i+1, "DB") || a.isDiretiva(i+1, "DS") || a.isDiretiva(i+1, "EQU")
+	}
+	return false
+}
+
+// isRotulo informa se o token i define um rótulo de dados: no .DATA, todo
+// identificador fora de um DB; no .CODE, apenas quando seguido de DB ou DS.
+func (a *Assembler) isRotulo(i int, secao string) bool {
+	if !a.isLabelDef(i) {
+		return false
+	}
+	return secao == "DATA" || a.isDiretiva(i+1, "DB") || a.isDiretiva(i+1, "DS")
+}
+
+// isDiretiva informa se o token i é a diretiva nome.
+func (a *Assembler) isDiretiva(i int, nome string) bool {
+	return i < len(a.Tokens) && a.Tokens[i].Tipo == TOKEN_DEFINE && a.Tokens[i].Valor == nome
+}
+
+// opcode procura a instrução na tabela do Neander e, no modo Ahmes, também
+// na tabela de instruções estendidas.
+func (a *Assembler) opcode(nome string) (uint8, error) {
+	if opcode, ok := Instructions[nome]; ok {
+		return opcode, nil
+	}
+	if opcode, ok := AhmesInstructions[nome]; ok {
+		if !a.Ahmes {
+			return 0, fmt.Errorf("instrução %s disponível apenas no modo AHMES", nome)
+		}
+		return opcode, nil
+	}
+	return 0, fmt.Errorf("instrução desconhecida: %s", nome)
+}
+
+func parseNumber(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 8)
+}
@@ -0,0 +1,186 @@
+package assembler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// caso é um programa .asm e as palavras esperadas a partir do endereço 0,
+// ou um trecho da mensagem de erro esperada.
+type caso struct {
+	nome     string
+	fonte    string
+	palavras []uint8
+	erro     string
+}
+
+func confere(t *testing.T, casos []caso) {
+	t.Helper()
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			a, err := Assemble(c.fonte, false, false)
+			if c.erro != "" {
+				if err == nil || !strings.Contains(err.Error(), c.erro) {
+					t.Fatalf("erro %v, esperado %q", err, c.erro)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			mem := a.palavras()
+			if !bytes.Equal(mem[:len(c.palavras)], c.palavras) {
+				t.Errorf("palavras % X, esperado % X", mem[:len(c.palavras)], c.palavras)
+			}
+		})
+	}
+}
+
+func TestExpressoes(t *testing.T) {
+	confere(t, []caso{
+		{nome: "rótulo mais número", fonte: ".CODE\nLDA T+1\nHLT\n.DATA\nT DB 01, 02\n", palavras: []uint8{0x20, 0x04, 0xF0, 0x01, 0x02}},
+		{nome: "diferença de rótulos", fonte: ".CODE\nINICIO: LDA N\nFIM: HLT\n.DATA\nN DB FIM-INICIO\n", palavras: []uint8{0x20, 0x03, 0xF0, 0x02}},
+		{nome: "referência adiante", fonte: ".CODE\nJMP FIM\nNOP\nFIM: HLT\n", palavras: []uint8{0x80, 0x03, 0x00, 0xF0}},
+		{nome: "subtração abaixo de zero", fonte: ".CODE\nLDA 0-1\n", palavras: []uint8{0x20, 0xFF}},
+		{nome: "constante EQU", fonte: "N EQU 10\n.CODE\nLDA N+2\n", palavras: []uint8{0x20, 0x12}},
+		{nome: "número negativo", fonte: ".CODE\nLDA -1\nHLT\n", erro: "token desconhecido: -1"},
+		{nome: "número com sinal", fonte: ".CODE\nLDA +5\n", erro: "token desconhecido: +5"},
+		{nome: "operador no fim", fonte: ".CODE\nLDA T-\n.DATA\nT DB 00\n", erro: "token desconhecido: T-"},
+		{nome: "rótulo desconhecido", fonte: ".CODE\nLDA X+1\n", erro: "label não definida: X"},
+		{nome: "número inválido", fonte: ".CODE\nLDA 100+1\n", erro: "número inválido: 100"},
+	})
+}
+
+// avalia não depende do lexer para recusar termos vazios.
+func TestAvaliaTermoVazio(t *testing.T) {
+	a := NewAssembler(nil)
+	for _, expr := range []string{"-1", "+5", "A-", "A--1"} {
+		if _, err := a.avalia(expr); err == nil || !strings.Contains(err.Error(), "termo vazio") {
+			t.Errorf("avalia(%q): erro %v, esperado termo vazio", expr, err)
+		}
+	}
+	if v, err := a.avalia("10-1"); err != nil || v != 0x0F {
+		t.Errorf("avalia(\"10-1\") = %02X, %v", v, err)
+	}
+}
+
+// Os erros trazem a linha e a coluna do token e são reportados todos de
+// uma vez.
+func TestErroPosicionado(t *testing.T) {
+	_, err := Assemble(".CODE\nNOP\nLDA X\nSUB 10\n", false, false)
+	if err == nil {
+		t.Fatal("esperado erro")
+	}
+	for _, e := range []string{"3:5: label não definida: X", "4:1: instrução SUB disponível apenas no modo AHMES"} {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("erros sem %q:\n%v", e, err)
+		}
+	}
+
+	// Um número com sinal para no lexer, também com a posição.
+	_, err = Assemble(".CODE\nNOP\nLDA -1\n", false, false)
+	if msg := fmt.Sprint(err); !strings.Contains(msg, "3:5: token desconhecido: -1") {
+		t.Errorf("erro sem a posição 3:5: %s", msg)
+	}
+}
+
+func TestAhmes(t *testing.T) {
+	confere(t, []caso{
+		{nome: "diretiva .AHMES", fonte: ".AHMES\n.CODE\nSUB X\nJB FIM\nSHL\nROR\nFIM: HLT\n.DATA\nX DB 01\n", palavras: []uint8{0x70, 0x07, 0xB8, 0x06, 0xE1, 0xE2, 0xF0, 0x01}},
+		{nome: "desvios", fonte: ".AHMES\n.CODE\nJP 0\nJV 0\nJNV 0\nJNZ 0\nJC 0\nJNC 0\nJNB 0\n", palavras: []uint8{0x94, 0, 0x98, 0, 0x9C, 0, 0xA4, 0, 0xB0, 0, 0xB4, 0, 0xBC, 0}},
+		{nome: "sem o modo AHMES", fonte: ".CODE\nSUB X\n.DATA\nX DB 01\n", erro: "instrução SUB disponível apenas no modo AHMES"},
+		{nome: "SUB como rótulo no Neander", fonte: ".CODE\nLDA SUB\n.DATA\nSUB DB 07\n", palavras: []uint8{0x20, 0x02, 0x07}},
+	})
+
+	a, err := Assemble(".CODE\nSHR\nROL\n", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem := a.palavras(); !a.Ahmes || mem[0] != 0xE0 || mem[1] != 0xE3 {
+		t.Errorf("modo AHMES pela opção: Ahmes=%v palavras % X", a.Ahmes, mem[:2])
+	}
+}
+
+func TestDados(t *testing.T) {
+	confere(t, []caso{
+		{nome: "DB com vários valores", fonte: ".CODE\nHLT\n.DATA\nT DB 01, 02, 0FF\nU DB 04\n", palavras: []uint8{0xF0, 0x01, 0x02, 0xFF, 0x04}},
+		{nome: "DB com rótulos e expressões", fonte: ".CODE\nHLT\n.DATA\nT DB T, T+2, N\nN EQU 7\n", palavras: []uint8{0xF0, 0x01, 0x03, 0x07}},
+		{nome: "string", fonte: ".CODE\nLDA S+1\n.DATA\nS DB \"Oi\", 0\n", palavras: []uint8{0x20, 0x03, 0x4F, 0x69, 0x00}},
+		{nome: "DS reserva espaço", fonte: ".CODE\nLDA V\nHLT\n.DATA\nBUF DS 3\nV DB 09\n", palavras: []uint8{0x20, 0x06, 0xF0, 0x00, 0x00, 0x00, 0x09}},
+		{nome: "DS com constante", fonte: "N EQU 2\n.CODE\nLDA V\n.DATA\nBUF DS N\nV DB 09\n", palavras: []uint8{0x20, 0x04, 0x00, 0x00, 0x09}},
+		{nome: "ORG", fonte: ".CODE\nLDA V\n.DATA\nORG 08\nV DB 09\n", palavras: []uint8{0x20, 0x08, 0, 0, 0, 0, 0, 0, 0x09}},
+		{nome: "EQU não ocupa memória", fonte: "A EQU 10\nB EQU A+1\n.CODE\nLDA B\nHLT\n", palavras: []uint8{0x20, 0x11, 0xF0, 0x00}},
+		{nome: "EQU redefinida", fonte: "A EQU 1\nA EQU 2\n.CODE\nHLT\n", erro: "símbolo já definido: A"},
+		{nome: "EQU com o nome de um rótulo", fonte: ".CODE\nA: HLT\nA EQU 2\n", erro: "símbolo já definido: A"},
+		{nome: "DB sem valor", fonte: ".CODE\nHLT\n.DATA\nT DB\n", erro: "esperado valor após DB"},
+		{nome: "DB terminado em vírgula", fonte: ".CODE\nHLT\n.DATA\nT DB 01,\n", erro: "esperado valor após"},
+		{nome: "DS sem tamanho", fonte: ".CODE\nHLT\n.DATA\nT DS\n", erro: "esperado número após DS"},
+		{nome: "string fora de DB", fonte: ".CODE\nLDA \"a\"\n", erro: "string fora de DB"},
+		{nome: "caractere fora de 8 bits", fonte: ".CODE\nHLT\n.DATA\nS DB \"5€\"\n", erro: "caractere fora da faixa de 8 bits"},
+		{nome: "vírgula solta", fonte: ".CODE\nLDA 01, 02\n", erro: "vírgula inesperada"},
+	})
+}
+
+func TestMemoria(t *testing.T) {
+	confere(t, []caso{
+		{nome: "ocupa a última posição", fonte: ".CODE\nORG 0FE\nLDA 01\n", palavras: []uint8{0x00}},
+		{nome: "dados sobre o código", fonte: ".CODE\nLDA X\nHLT\n.DATA\nORG 01\nX DB 05\n", erro: "6:6: sobreposição no endereço 01, já ocupado pela linha 2"},
+		{nome: "código sobre os dados", fonte: ".DATA\nORG 01\nX DB 05\n.CODE\nORG 00\nLDA X\n", erro: "6:5: sobreposição no endereço 01, já ocupado pela linha 3"},
+		{nome: "DS sobre o código", fonte: ".CODE\nNOP\nNOP\n.DATA\nORG 01\nT DS 1\n", erro: "sobreposição no endereço 01, já ocupado pela linha 3"},
+		{nome: "código além de FF", fonte: ".CODE\nORG 0FE\nLDA 01\nHLT\n", erro: "o programa excede a memória: endereço 100 depois da última posição (FF)"},
+		{nome: "DS além de FF", fonte: ".DATA\nORG 0FF\nT DS 2\n", erro: "endereço 100 depois da última posição"},
+		{nome: "ORG além de FF", fonte: ".CODE\nORG 100\n", erro: "valor inválido após ORG"},
+	})
+
+	// O estouro é reportado uma única vez, não uma vez por palavra.
+	_, err := Assemble(".CODE\nORG 0FF\nLDA 01\nLDA 02\nHLT\n", false, false)
+	if n := strings.Count(fmt.Sprint(err), "excede a memória"); n != 1 {
+		t.Errorf("estouro reportado %d vezes:\n%v", n, err)
+	}
+}
+
+func TestRelocaDados(t *testing.T) {
+	casos := []struct {
+		nome     string
+		fonte    string
+		palavras []uint8
+		// sobrepoe indica que, sem RelocaDados, os dados cairiam sobre o
+		// código.
+		sobrepoe bool
+	}{
+		{nome: "ORG 20 do compilador", fonte: ".CODE\nLDA X\nHLT\n.DATA\nORG 20\nX DB 05\n", palavras: []uint8{0x20, 0x03, 0xF0, 0x05}},
+		{nome: "dados antes do código", fonte: ".DATA\nORG 40\nA DB 01\n.CODE\nLDA A\nHLT\n", palavras: []uint8{0x20, 0x03, 0xF0, 0x01}},
+		{nome: "várias seções", fonte: ".CODE\nLDA A\n.DATA\nA DB 01\n.CODE\nADD B\n.DATA\nORG 80\nB DB 02\n", palavras: []uint8{0x20, 0x04, 0x30, 0x05, 0x01, 0x02}},
+		{nome: "código que invadiria os dados", fonte: ".CODE\n" + strings.Repeat("NOP\n", 0x21) + "LDA X\n.DATA\nORG 20\nX DB 05\n", palavras: append(make([]uint8, 0x21), 0x20, 0x23, 0x05), sobrepoe: true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if _, err := Assemble(c.fonte, false, false); c.sobrepoe && !strings.Contains(fmt.Sprint(err), "sobreposição") {
+				t.Errorf("sem RelocaDados: erro %v, esperado sobreposição", err)
+			}
+			a, err := Assemble(c.fonte, false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mem := a.palavras(); !bytes.Equal(mem[:len(c.palavras)], c.palavras) {
+				t.Errorf("palavras % X, esperado % X", mem[:len(c.palavras)], c.palavras)
+			}
+		})
+	}
+}
+
+func TestRotulos(t *testing.T) {
+	confere(t, []caso{
+		{nome: "rótulo em linha própria", fonte: ".CODE\nJMP FIM\nFIM:\nHLT\n", palavras: []uint8{0x80, 0x02, 0xF0}},
+		{nome: "vários rótulos no mesmo endereço", fonte: ".CODE\nA:\nB: JMP A\nJMP B\n", palavras: []uint8{0x80, 0x00, 0x80, 0x00}},
+		{nome: "laço para trás", fonte: ".CODE\nVOLTA: LDA X\nJN VOLTA\nHLT\n.DATA\nX DB 80\n", palavras: []uint8{0x20, 0x05, 0x90, 0x00, 0xF0, 0x80}},
+		{nome: "rótulo que parece número", fonte: ".CODE\nJMP CAFE\nCAFE: HLT\n", palavras: []uint8{0x80, 0x02, 0xF0}},
+		{nome: "endereço numérico", fonte: ".CODE\nJZ 10\n", palavras: []uint8{0xA0, 0x10}},
+		{nome: "desvio para rótulo desconhecido", fonte: ".CODE\nJMP FIM\n", erro: "2:5: label não definida: FIM"},
+		{nome: "rótulo duplicado", fonte: ".CODE\nA: NOP\nA: HLT\n", erro: "3:1: símbolo já definido: A"},
+		{nome: "rótulo com o nome de uma variável", fonte: ".CODE\nX: HLT\n.DATA\nX DB 01\n", erro: "símbolo já definido: X"},
+		{nome: "mnemônico desconhecido", fonte: ".CODE\nPULA FIM\nFIM: HLT\n", erro: "2:1: label não definida: PULA"},
+	})
+}
@@ -0,0 +1,166 @@
+package assembler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Formatos associa cada formato de saída aceito por WriteFormat à extensão
+// usada por padrão no nome do arquivo.
+var Formatos = map[string]string{
+	"mem":     ".mem",  // imagem do simulador Neander (cabeçalho + palavras de 2 bytes)
+	"hex":     ".hex",  // Intel HEX, um byte por palavra
+	"bin":     ".bin",  // binário puro de 256 bytes, sem cabeçalho
+	"logisim": ".img",  // imagem "v2.0 raw" para a RAM/ROM do Logisim
+	"verilog": ".vmem", // texto para $readmemh
+}
+
+// NomesFormatos devolve os nomes dos formatos em ordem alfabética.
+func NomesFormatos() []string {
+	nomes := make([]string, 0, len(Formatos))
+	for nome := range Formatos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	return nomes
+}
+
+// palavras devolve as 256 palavras da memória, um byte por endereço.
+func (a *Assembler) palavras() []uint8 {
+	mem := make([]uint8, 256)
+	for i := range mem {
+		if i*2 < len(a.Output) {
+			mem[i] = a.Output[i*2]
+		}
+	}
+	return mem
+}
+
+// WriteFormat grava a memória montada em filename no formato pedido.
+func (a *Assembler) WriteFormat(filename string, formato string) error {
+	if _, ok := Formatos[formato]; !ok {
+		return fmt.Errorf("formato desconhecido: %s (use %s)", formato, strings.Join(NomesFormatos(), ", "))
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return a.EscreveFormato(file, formato)
+}
+
+// EscreveFormato escreve a memória montada em w no formato pedido.
+func (a *Assembler) EscreveFormato(out io.Writer, formato string) error {
+	var err error
+	w := bufio.NewWriter(out)
+	switch formato {
+	case "mem":
+		err = a.EscreveMEM(w)
+	case "hex":
+		err = a.EscreveIntelHex(w)
+	case "bin":
+		err = a.EscreveBin(w)
+	case "logisim":
+		err = a.EscreveLogisim(w)
+	case "verilog":
+		err = a.EscreveVerilog(w)
+	default:
+		return fmt.Errorf("formato desconhecido: %s (use %s)", formato, strings.Join(NomesFormatos(), ", "))
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// WriteMEM grava o arquivo .mem com um cabeçalho fixo (4 bytes) e preenche até 516 bytes.
+// No modo Ahmes o cabeçalho identifica a imagem como Ahmes para o emulador.
+func (a *Assembler) WriteMEM(filename string) error {
+	return a.WriteFormat(filename, "mem")
+}
+
+// EscreveMEM escreve a imagem no formato do simulador Neander.
+func (a *Assembler) EscreveMEM(w io.Writer) error {
+	header := []uint8{0x03, 0x4E, 0x44, 0x52} // Cabeçalho fixo ("NDR")
+	if a.Ahmes {
+		header = []uint8{0x03, 0x41, 0x48, 0x4D} // Cabeçalho do Ahmes ("AHM")
+	}
+	output := append(header, a.Output...)
+
+	for len(output) < 516 {
+		output = append(output, 0x00)
+	}
+
+	_, err := w.Write(output)
+	return err
+}
+
+// EscreveBin escreve as 256 palavras como bytes, sem cabeçalho.
+func (a *Assembler) EscreveBin(w io.Writer) error {
+	_, err := w.Write(a.palavras())
+	return err
+}
+
+// EscreveIntelHex escreve a memória em registros Intel HEX de 16 bytes,
+// endereçados por palavra, terminando com o registro de fim de arquivo.
+func (a *Assembler) EscreveIntelHex(w io.Writer) error {
+	mem := a.palavras()
+	for addr := 0; addr < len(mem); addr += 16 {
+		linha := mem[addr : addr+16]
+		soma := uint8(len(linha)) + uint8(addr>>8) + uint8(addr)
+		var sb strings.Builder
+		fmt.Fprintf(&sb, ":%02X%04X00", len(linha), addr)
+		for _, b := range linha {
+			fmt.Fprintf(&sb, "%02X", b)
+			soma += b
+		}
+		// Checksum: complemento de dois da soma de todos os bytes do registro.
+		fmt.Fprintf(&sb, "%02X\n", uint8(-soma))
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, ":00000001FF\n")
+	return err
+}
+
+// EscreveLogisim escreve a imagem no formato "v2.0 raw" lido pelo
+// "Load Image" das memórias do Logisim, 16 palavras por linha.
+func (a *Assembler) EscreveLogisim(w io.Writer) error {
+	if _, err := io.WriteString(w, "v2.0 raw\n"); err != nil {
+		return err
+	}
+	return escreveLinhasHex(w, a.palavras(), "")
+}
+
+// EscreveVerilog escreve a imagem para ser carregada com $readmemh, 16
+// palavras por linha, cada linha precedida pelo endereço (@xx).
+func (a *Assembler) EscreveVerilog(w io.Writer) error {
+	if _, err := io.WriteString(w, "// memória do Neander: $readmemh(\"arquivo.vmem\", mem)\n"); err != nil {
+		return err
+	}
+	return escreveLinhasHex(w, a.palavras(), "@")
+}
+
+// escreveLinhasHex escreve mem em linhas de 16 valores hexadecimais. Se
+// prefixoEnd não for vazio, cada linha começa com o endereço precedido dele.
+func escreveLinhasHex(w io.Writer, mem []uint8, prefixoEnd string) error {
+	for addr := 0; addr < len(mem); addr += 16 {
+		var campos []string
+		if prefixoEnd != "" {
+			campos = append(campos, fmt.Sprintf("%s%02X", prefixoEnd, addr))
+		}
+		for _, b := range mem[addr : addr+16] {
+			campos = append(campos, fmt.Sprintf("%02x", b))
+		}
+		if _, err := io.WriteString(w, strings.Join(campos, " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
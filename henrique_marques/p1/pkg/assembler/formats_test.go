@@ -0,0 +1,146 @@
+package assembler
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// O programa de teste ocupa 00..03 e tem um dado em 12, na segunda linha
+// dos formatos de 16 palavras.
+const fonteFormatos = ".CODE\nLDA X\nNOT\nHLT\n.DATA\nORG 12\nX DB 0AB\n"
+
+func monteFormatos(t *testing.T, ahmes bool) *Assembler {
+	t.Helper()
+	a, err := Assemble(fonteFormatos, ahmes, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func escreve(t *testing.T, a *Assembler, formato string) string {
+	t.Helper()
+	var saida bytes.Buffer
+	if err := a.EscreveFormato(&saida, formato); err != nil {
+		t.Fatal(err)
+	}
+	return saida.String()
+}
+
+func TestFormatosTexto(t *testing.T) {
+	a := monteFormatos(t, false)
+	casos := []struct {
+		formato string
+		linhas  int
+		inicio  []string
+		fim     string
+	}{
+		{formato: "hex", linhas: 17, inicio: []string{
+			":10000000201260F00000000000000000000000006E",
+			":100010000000AB0000000000000000000000000035",
+		}, fim: ":00000001FF"},
+		{formato: "logisim", linhas: 17, inicio: []string{
+			"v2.0 raw",
+			"20 12 60 f0 00 00 00 00 00 00 00 00 00 00 00 00",
+			"00 00 ab 00 00 00 00 00 00 00 00 00 00 00 00 00",
+		}, fim: "00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00"},
+		{formato: "verilog", linhas: 17, inicio: []string{
+			`// memória do Neander: $readmemh("arquivo.vmem", mem)`,
+			"@00 20 12 60 f0 00 00 00 00 00 00 00 00 00 00 00 00",
+			"@10 00 00 ab 00 00 00 00 00 00 00 00 00 00 00 00 00",
+		}, fim: "@F0 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00"},
+	}
+	for _, c := range casos {
+		t.Run(c.formato, func(t *testing.T) {
+			saida := escreve(t, a, c.formato)
+			linhas := strings.Split(strings.TrimSuffix(saida, "\n"), "\n")
+			if len(linhas) != c.linhas || !strings.HasSuffix(saida, "\n") {
+				t.Fatalf("%d linhas, esperado %d:\n%s", len(linhas), c.linhas, saida)
+			}
+			for i, linha := range c.inicio {
+				if linhas[i] != linha {
+					t.Errorf("linha %d: %q, esperado %q", i+1, linhas[i], linha)
+				}
+			}
+			if linhas[len(linhas)-1] != c.fim {
+				t.Errorf("última linha: %q, esperado %q", linhas[len(linhas)-1], c.fim)
+			}
+		})
+	}
+}
+
+// Em um registro Intel HEX válido, a soma de todos os bytes, incluindo o
+// checksum, é zero.
+func TestIntelHexChecksum(t *testing.T) {
+	for _, registro := range strings.Fields(escreve(t, monteFormatos(t, false), "hex")) {
+		dados, err := hex.DecodeString(strings.TrimPrefix(registro, ":"))
+		if err != nil {
+			t.Fatalf("%s: %v", registro, err)
+		}
+		var soma uint8
+		for _, b := range dados {
+			soma += b
+		}
+		if soma != 0 || int(dados[0]) != len(dados)-5 {
+			t.Errorf("registro inválido: %s", registro)
+		}
+	}
+}
+
+func TestFormatosBinarios(t *testing.T) {
+	palavras := make([]byte, 256)
+	copy(palavras, []byte{0x20, 0x12, 0x60, 0xF0})
+	palavras[0x12] = 0xAB
+
+	if bin := escreve(t, monteFormatos(t, false), "bin"); bin != string(palavras) {
+		t.Errorf("bin: % X", bin)
+	}
+
+	for _, c := range []struct {
+		ahmes     bool
+		cabecalho string
+	}{{false, "\x03NDR"}, {true, "\x03AHM"}} {
+		mem := escreve(t, monteFormatos(t, c.ahmes), "mem")
+		if len(mem) != 516 || mem[:4] != c.cabecalho {
+			t.Fatalf("mem: %d bytes, cabeçalho %q", len(mem), mem[:4])
+		}
+		for i, p := range palavras {
+			if mem[4+i*2] != p || mem[5+i*2] != 0 {
+				t.Fatalf("mem: palavra %02X = % X, esperado %02X 00", i, mem[4+i*2:6+i*2], p)
+			}
+		}
+	}
+}
+
+func TestFormatoDesconhecido(t *testing.T) {
+	a := monteFormatos(t, false)
+	esperado := "formato desconhecido: ihex (use bin, hex, logisim, mem, verilog)"
+	if err := a.EscreveFormato(&bytes.Buffer{}, "ihex"); err == nil || err.Error() != esperado {
+		t.Errorf("EscreveFormato: %v", err)
+	}
+	arquivo := filepath.Join(t.TempDir(), "programa.ihex")
+	if err := a.WriteFormat(arquivo, "ihex"); err == nil || err.Error() != esperado {
+		t.Errorf("WriteFormat: %v", err)
+	}
+	if _, err := os.Stat(arquivo); !os.IsNotExist(err) {
+		t.Error("WriteFormat criou o arquivo de um formato desconhecido")
+	}
+}
+
+func TestWriteFormat(t *testing.T) {
+	a := monteFormatos(t, false)
+	for _, formato := range NomesFormatos() {
+		arquivo := filepath.Join(t.TempDir(), "programa"+Formatos[formato])
+		if err := a.WriteFormat(arquivo, formato); err != nil {
+			t.Fatal(err)
+		}
+		gravado, err := os.ReadFile(arquivo)
+		if err != nil || string(gravado) != escreve(t, a, formato) {
+			t.Errorf("%s: arquivo difere de EscreveFormato (%v)", formato, err)
+		}
+	}
+}
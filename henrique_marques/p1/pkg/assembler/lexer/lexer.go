@@ -1,102 +1,218 @@
-package lexer
-
-import (
-	"log"
-	"os"
-	"regexp"
-	"strconv"
-	"strings"
-)
-
-const (
-	TOKEN_SECTION  = "SECTION"
-	TOKEN_EOF      = "EOF"
-	TOKEN_INSTR    = "INSTRUCTION"
-	TOKEN_NUMBER   = "NUMBER"
-	TOKEN_VAR      = "VARIABLE"
-	TOKEN_DEFINE   = "DEFINE"
-	TOKEN_UNKNOWN  = "UNKNOWN"
-)
-
-var (
-	Instructions = map[string]uint8{
-		"NOP": 0x00, "STA": 0x10, "LDA": 0x20, "ADD": 0x30,
-		"OR": 0x40, "AND": 0x50, "NOT": 0x60, "JMP": 0x80,
-		"JN": 0x90, "JZ": 0xA0, "HLT": 0xF0,
-	}
-
-	Define = map[string]bool{
-		"DB": true, "DS": false, "ORG": true,
-	}
-
-	varRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
-)
-
-type Token struct {
-	Tipo  string
-	Valor string
-}
-
-func isInstruction(lexema string) bool {
-	_, existe := Instructions[lexema]
-	return existe
-}
-
-func isDefine(lexema string) bool {
-	_, existe := Define[lexema]
-	return existe
-}
-
-func isNumber(lexema string) bool {
-	if _, err := strconv.ParseInt(lexema, 16, 64); err == nil {
-		return true
-	}
-	return false
-}
-
-func isVariable(lexema string) bool {
-	return varRegex.MatchString(lexema)
-}
-
-func lexer(lexema string) Token {
-	switch {
-	case strings.HasPrefix(lexema, "."):
-		return Token{Tipo: TOKEN_SECTION, Valor: strings.TrimPrefix(lexema, ".")}
-	case isInstruction(lexema):
-		return Token{Tipo: TOKEN_INSTR, Valor: lexema}
-	case isDefine(lexema):
-		return Token{Tipo: TOKEN_DEFINE, Valor: lexema}
-	case isNumber(lexema):
-		return Token{Tipo: TOKEN_NUMBER, Valor: lexema}
-	case isVariable(lexema):
-		return Token{Tipo: TOKEN_VAR, Valor: lexema}
-	default:
-		log.Printf("Token desconhecido: %s", lexema)
-		return Token{Tipo: TOKEN_UNKNOWN, Valor: lexema}
-	}
-}
-
-func GetTokens(caminhoArquivo string) (tokens []Token) {
-	arquivo, err := os.ReadFile(caminhoArquivo)
-	if err != nil {
-		log.Fatalf("Não foi possível ler o arquivo: %v", err)
-	}
-
-	linhas := strings.Split(string(arquivo), "\n")
-
-	for _, linha := range linhas {
-		linha = strings.Split(linha, ";")[0] // Remove comentários
-		re := regexp.MustCompile(`\s+`)
-		lexemas := re.Split(strings.TrimSpace(linha), -1)
-
-		for _, lexema := range lexemas {
-			if lexema != "" {
-				tokens = append(tokens, lexer(lexema))
-			}
-		}
-	}
-
-	tokens = append(tokens, Token{Tipo: TOKEN_EOF, Valor: ""})
-
-	return
-}
\ No newline at end of file
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"p1/pkg/diag"
+)
+
+const (
+	TOKEN_SECTION = "SECTION"
+	TOKEN_EOF     = "EOF"
+	TOKEN_INSTR   = "INSTRUCTION"
+	TOKEN_NUMBER  = "NUMBER"
+	TOKEN_VAR     = "VARIABLE"
+	TOKEN_DEFINE  = "DEFINE"
+	TOKEN_UNKNOWN = "UNKNOWN"
+	TOKEN_STRING  = "STRING"
+	TOKEN_COMMA   = "COMMA"
+	TOKEN_LABEL   = "LABEL"
+	TOKEN_EXPR    = "EXPRESSION"
+)
+
+var (
+	Instructions = map[string]uint8{
+		"NOP": 0x00, "STA": 0x10, "LDA": 0x20, "ADD": 0x30,
+		"OR": 0x40, "AND": 0x50, "NOT": 0x60, "JMP": 0x80,
+		"JN": 0x90, "JZ": 0xA0, "HLT": 0xF0,
+	}
+
+	// AhmesInstructions só são reconhecidas depois da diretiva .AHMES ou
+	// quando o modo Ahmes é pedido na chamada de GetTokens.
+	AhmesInstructions = map[string]uint8{
+		"SUB": 0x70, "JP": 0x94, "JV": 0x98, "JNV": 0x9C,
+		"JNZ": 0xA4, "JC": 0xB0, "JNC": 0xB4, "JB": 0xB8,
+		"JNB": 0xBC, "SHR": 0xE0, "SHL": 0xE1, "ROR": 0xE2,
+		"ROL": 0xE3,
+	}
+
+	Define = map[string]bool{
+		"DB": true, "DS": true, "ORG": true, "EQU": true,
+		"MACRO": true, "ENDM": true,
+	}
+
+	varRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// Uma expressão soma ou subtrai símbolos e números, sem espaços:
+	// TABELA+1, FIM-INICIO.
+	exprRegex = regexp.MustCompile(`^[A-Za-z0-9_]+([+-][A-Za-z0-9_]+)+$`)
+	// Um lexema é uma string entre aspas (possivelmente sem a aspa final),
+	// uma vírgula ou qualquer sequência sem espaços nem vírgulas.
+	lexemaRegex = regexp.MustCompile(`"[^"]*"?|,|[^\s,]+`)
+)
+
+type Token struct {
+	Tipo  string
+	Valor string
+	Pos   diag.Pos
+}
+
+func isInstruction(lexema string, ahmes bool) bool {
+	if _, existe := Instructions[lexema]; existe {
+		return true
+	}
+	_, existe := AhmesInstructions[lexema]
+	return ahmes && existe
+}
+
+func isDefine(lexema string) bool {
+	_, existe := Define[lexema]
+	return existe
+}
+
+func isNumber(lexema string) bool {
+	// ParseUint recusa sinais: "-1" e "+5" não são números.
+	if _, err := strconv.ParseUint(lexema, 16, 64); err == nil {
+		return true
+	}
+	return false
+}
+
+// Hex escreve v em hexadecimal, com dois dígitos, de forma que o lexer o
+// reconheça como número. Valores cujo texto coincide com uma diretiva ou
+// instrução, como DB, ganham um zero à esquerda: 0DB.
+func Hex(v uint8) string {
+	s := fmt.Sprintf("%02X", v)
+	if isDefine(s) || isInstruction(s, true) {
+		return "0" + s
+	}
+	return s
+}
+
+func isVariable(lexema string) bool {
+	return varRegex.MatchString(lexema)
+}
+
+func lexer(lexema string, ahmes bool) Token {
+	switch {
+	case lexema == ",":
+		return Token{Tipo: TOKEN_COMMA, Valor: lexema}
+	case strings.HasPrefix(lexema, "\""):
+		if len(lexema) < 2 || !strings.HasSuffix(lexema, "\"") {
+			return Token{Tipo: TOKEN_UNKNOWN, Valor: lexema}
+		}
+		return Token{Tipo: TOKEN_STRING, Valor: lexema[1 : len(lexema)-1]}
+	case strings.HasPrefix(lexema, "."):
+		return Token{Tipo: TOKEN_SECTION, Valor: strings.TrimPrefix(lexema, ".")}
+	case strings.HasSuffix(lexema, ":") && isVariable(strings.TrimSuffix(lexema, ":")):
+		return Token{Tipo: TOKEN_LABEL, Valor: strings.TrimSuffix(lexema, ":")}
+	case isInstruction(lexema, ahmes):
+		return Token{Tipo: TOKEN_INSTR, Valor: lexema}
+	case isDefine(lexema):
+		return Token{Tipo: TOKEN_DEFINE, Valor: lexema}
+	case exprRegex.MatchString(lexema):
+		return Token{Tipo: TOKEN_EXPR, Valor: lexema}
+	case isNumber(lexema):
+		return Token{Tipo: TOKEN_NUMBER, Valor: lexema}
+	case isVariable(lexema):
+		return Token{Tipo: TOKEN_VAR, Valor: lexema}
+	default:
+		return Token{Tipo: TOKEN_UNKNOWN, Valor: lexema}
+	}
+}
+
+// GetTokens lê o arquivo .asm e devolve seus tokens. Se ahmes for verdadeiro
+// as instruções do Ahmes são reconhecidas desde o início do arquivo; caso
+// contrário, apenas após a diretiva .AHMES.
+func GetTokens(caminhoArquivo string, ahmes bool) ([]Token, error) {
+	arquivo, err := os.Open(caminhoArquivo)
+	if err != nil {
+		return nil, err
+	}
+	defer arquivo.Close()
+	return tokenize(arquivo, ahmes)
+}
+
+// Tokenize lê o código-fonte .asm de r e devolve seus tokens. As instruções
+// do Ahmes são reconhecidas apenas após a diretiva .AHMES. Tokens
+// desconhecidos e strings não terminadas são devolvidos como TOKEN_UNKNOWN e
+// reportados, com a posição, em uma diag.Lista.
+func Tokenize(r io.Reader) ([]Token, error) {
+	return tokenize(r, false)
+}
+
+// TokenizeAhmes é como Tokenize, mas reconhece as instruções do Ahmes desde
+// o início do código.
+func TokenizeAhmes(r io.Reader) ([]Token, error) {
+	return tokenize(r, true)
+}
+
+func tokenize(r io.Reader, ahmes bool) ([]Token, error) {
+	fonte, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []Token
+	var erros diag.Lista
+	linhas := strings.Split(string(fonte), "\n")
+
+	for n, linha := range linhas {
+		linha = removeComentario(linha)
+
+		for _, idx := range lexemaRegex.FindAllStringIndex(linha, -1) {
+			lexema := linha[idx[0]:idx[1]]
+			token := lexer(lexema, ahmes)
+			token.Pos = diag.Pos{Linha: n + 1, Coluna: utf8.RuneCountInString(linha[:idx[0]]) + 1}
+			switch {
+			case token.Tipo == TOKEN_SECTION && strings.ToUpper(token.Valor) == "AHMES":
+				ahmes = true
+			case token.Tipo == TOKEN_UNKNOWN && strings.HasPrefix(lexema, "\""):
+				erros.Add(token.Pos, "string não terminada")
+			case token.Tipo == TOKEN_UNKNOWN:
+				erros.Add(token.Pos, "token desconhecido: %s", lexema)
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	tokens = append(tokens, Token{Tipo: TOKEN_EOF, Valor: "", Pos: diag.Pos{Linha: len(linhas), Coluna: 1}})
+
+	return tokens, erros.Err()
+}
+
+// removeComentario corta a linha no primeiro ';' que não esteja dentro de
+// uma string.
+func removeComentario(linha string) string {
+	return linha[:inicioComentario(linha)]
+}
+
+// Comentario devolve o texto do comentário da linha, sem o ';' e sem
+// espaços nas pontas, ou "" se não houver.
+func Comentario(linha string) string {
+	i := inicioComentario(linha)
+	if i == len(linha) {
+		return ""
+	}
+	return strings.TrimSpace(linha[i+1:])
+}
+
+// inicioComentario devolve o índice do primeiro ';' fora de strings, ou o
+// tamanho da linha.
+func inicioComentario(linha string) int {
+	aspas := false
+	for i, c := range linha {
+		switch {
+		case c == '"':
+			aspas = !aspas
+		case c == ';' && !aspas:
+			return i
+		}
+	}
+	return len(linha)
+}
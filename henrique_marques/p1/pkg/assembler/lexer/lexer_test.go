@@ -0,0 +1,122 @@
+package lexer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLexema(t *testing.T) {
+	casos := []struct {
+		lexema string
+		ahmes  bool
+		tipo   string
+		valor  string
+	}{
+		{lexema: "LDA", tipo: TOKEN_INSTR, valor: "LDA"},
+		{lexema: "SUB", tipo: TOKEN_VAR, valor: "SUB"},
+		{lexema: "SUB", ahmes: true, tipo: TOKEN_INSTR, valor: "SUB"},
+		{lexema: "DB", tipo: TOKEN_DEFINE, valor: "DB"},
+		{lexema: "1F", tipo: TOKEN_NUMBER, valor: "1F"},
+		{lexema: "CAFE", tipo: TOKEN_NUMBER, valor: "CAFE"},
+		{lexema: "X1", tipo: TOKEN_VAR, valor: "X1"},
+		{lexema: "FIM:", tipo: TOKEN_LABEL, valor: "FIM"},
+		{lexema: ".DATA", tipo: TOKEN_SECTION, valor: "DATA"},
+		{lexema: "T+1", tipo: TOKEN_EXPR, valor: "T+1"},
+		{lexema: "FIM-INICIO", tipo: TOKEN_EXPR, valor: "FIM-INICIO"},
+		{lexema: `"oi"`, tipo: TOKEN_STRING, valor: "oi"},
+		{lexema: `"oi`, tipo: TOKEN_UNKNOWN, valor: `"oi`},
+		{lexema: ",", tipo: TOKEN_COMMA, valor: ","},
+		{lexema: "-1", tipo: TOKEN_UNKNOWN, valor: "-1"},
+		{lexema: "+5", tipo: TOKEN_UNKNOWN, valor: "+5"},
+		{lexema: "T-", tipo: TOKEN_UNKNOWN, valor: "T-"},
+		{lexema: "@", tipo: TOKEN_UNKNOWN, valor: "@"},
+	}
+	for _, c := range casos {
+		token := lexer(c.lexema, c.ahmes)
+		if token.Tipo != c.tipo || token.Valor != c.valor {
+			t.Errorf("lexer(%q, %v) = %s %q, esperado %s %q", c.lexema, c.ahmes, token.Tipo, token.Valor, c.tipo, c.valor)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(strings.NewReader(".CODE\nLDA X ; comentário\n.DATA\nX DB 01, \"a,b\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obtidos []string
+	for _, token := range tokens {
+		obtidos = append(obtidos, token.Tipo+":"+token.Valor)
+	}
+	esperados := []string{
+		"SECTION:CODE", "INSTRUCTION:LDA", "VARIABLE:X",
+		"SECTION:DATA", "VARIABLE:X", "DEFINE:DB", "NUMBER:01", "COMMA:,", "STRING:a,b", "EOF:",
+	}
+	if strings.Join(obtidos, " ") != strings.Join(esperados, " ") {
+		t.Errorf("tokens:\n%s\nesperados:\n%s", strings.Join(obtidos, " "), strings.Join(esperados, " "))
+	}
+	if tokens[1].Pos.Linha != 2 || tokens[1].Pos.Coluna != 1 {
+		t.Errorf("LDA em %d:%d, esperado 2:1", tokens[1].Pos.Linha, tokens[1].Pos.Coluna)
+	}
+}
+
+func TestTokenizeErros(t *testing.T) {
+	casos := []struct {
+		fonte string
+		erro  string
+	}{
+		{fonte: "LDA -1\n", erro: "1:5: token desconhecido: -1"},
+		{fonte: "\nX DB \"aberta\n", erro: "2:6: string não terminada"},
+		{fonte: "SUB X\n", erro: ""},
+		{fonte: ".AHMES\nSUB X\n", erro: ""},
+	}
+	for _, c := range casos {
+		_, err := Tokenize(strings.NewReader(c.fonte))
+		switch {
+		case c.erro == "" && err != nil:
+			t.Errorf("%q: erro inesperado: %v", c.fonte, err)
+		case c.erro != "" && (err == nil || !strings.Contains(err.Error(), c.erro)):
+			t.Errorf("%q: erro %v, esperado %q", c.fonte, err, c.erro)
+		}
+	}
+}
+
+// Hex nunca produz um texto que o lexer leia como outra coisa que não o
+// mesmo número.
+func TestHex(t *testing.T) {
+	for v := 0; v < 256; v++ {
+		texto := Hex(uint8(v))
+		token := lexer(texto, true)
+		n, err := strconv.ParseUint(texto, 16, 8)
+		if token.Tipo != TOKEN_NUMBER || err != nil || n != uint64(v) {
+			t.Errorf("Hex(%02X) = %q, lido como %s", v, texto, token.Tipo)
+		}
+	}
+	if Hex(0xDB) != "0DB" || Hex(0x0A) != "0A" {
+		t.Errorf("Hex(DB) = %q, Hex(0A) = %q", Hex(0xDB), Hex(0x0A))
+	}
+}
+
+func TestGetTokens(t *testing.T) {
+	arquivo := filepath.Join(t.TempDir(), "programa.asm")
+	if err := os.WriteFile(arquivo, []byte(".CODE\nSUB X\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := GetTokens(arquivo, true)
+	if err != nil || len(tokens) < 2 || tokens[1].Tipo != TOKEN_INSTR {
+		t.Errorf("GetTokens(ahmes) = %v, %v", tokens, err)
+	}
+
+	if _, err := GetTokens(filepath.Join(t.TempDir(), "nada.asm"), false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("arquivo inexistente: erro %v", err)
+	}
+	falha := errors.New("falha de leitura")
+	if _, err := Tokenize(iotest.ErrReader(falha)); !errors.Is(err, falha) {
+		t.Errorf("leitor com erro: erro %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package assembler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// palavrasPorLinha é quantas palavras cabem na coluna PALAVRAS.
+const palavrasPorLinha = 4
+
+type palavra struct {
+	addr  uint8
+	valor uint8
+}
+
+// Listing monta a listagem do programa: para cada linha de fonte, o
+// endereço e as palavras emitidas seguidos do texto original (com
+// comentários), e ao final a tabela de símbolos com o endereço e o valor de
+// cada rótulo, seguida das constantes definidas com EQU. Deve ser chamada depois da SecondPass.
+func (a *Assembler) Listing(fonte string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-4s  %-12s  %5s  %s\n", "END", "PALAVRAS", "LINHA", "FONTE")
+
+	linhas := strings.Split(strings.ReplaceAll(fonte, "\r\n", "\n"), "\n")
+	for i, linha := range linhas {
+		emitidas := a.emitidas[i+1]
+		if len(emitidas) == 0 {
+			fmt.Fprintf(&sb, "%-4s  %-12s  %5d  %s\n", "", "", i+1, linha)
+			continue
+		}
+		// Linhas que geram muitas palavras (strings, macros) continuam nas
+		// linhas seguintes da listagem, palavrasPorLinha de cada vez.
+		for inicio := 0; inicio < len(emitidas); inicio += palavrasPorLinha {
+			fim := min(inicio+palavrasPorLinha, len(emitidas))
+			valores := []string{}
+			for _, p := range emitidas[inicio:fim] {
+				valores = append(valores, fmt.Sprintf("%02X", p.valor))
+			}
+			end := fmt.Sprintf("%02X", emitidas[inicio].addr)
+			if inicio == 0 {
+				fmt.Fprintf(&sb, "%-4s  %-12s  %5d  %s\n", end, strings.Join(valores, " "), i+1, linha)
+			} else {
+				fmt.Fprintf(&sb, "%-4s  %s\n", end, strings.Join(valores, " "))
+			}
+		}
+	}
+
+	nomes := make([]string, 0, len(a.Labels))
+	for nome := range a.Labels {
+		nomes = append(nomes, nome)
+	}
+	sort.Slice(nomes, func(i, j int) bool {
+		if a.Labels[nomes[i]] != a.Labels[nomes[j]] {
+			return a.Labels[nomes[i]] < a.Labels[nomes[j]]
+		}
+		return nomes[i] < nomes[j]
+	})
+
+	fmt.Fprintf(&sb, "\nTABELA DE SÍMBOLOS\n%-16s  %-4s  %s\n", "RÓTULO", "END", "VALOR")
+	for _, nome := range nomes {
+		addr := a.Labels[nome]
+		valor := "--"
+		if int(addr)*2 < len(a.Output) {
+			valor = fmt.Sprintf("%02X", a.Output[int(addr)*2])
+		}
+		fmt.Fprintf(&sb, "%-16s  %02X    %s\n", nome, addr, valor)
+	}
+
+	if len(a.Constantes) > 0 {
+		constantes := make([]string, 0, len(a.Constantes))
+		for nome := range a.Constantes {
+			constantes = append(constantes, nome)
+		}
+		sort.Strings(constantes)
+		fmt.Fprintf(&sb, "\nCONSTANTES (EQU)\n%-16s  %s\n", "NOME", "VALOR")
+		for _, nome := range constantes {
+			fmt.Fprintf(&sb, "%-16s  %02X\n", nome, a.Constantes[nome])
+		}
+	}
+	return sb.String()
+}
+
+// WriteLST grava a listagem do programa no arquivo .lst.
+func (a *Assembler) WriteLST(filename string, fonte string) error {
+	return os.WriteFile(filename, []byte(a.Listing(fonte)), 0644)
+}
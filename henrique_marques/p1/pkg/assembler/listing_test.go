@@ -0,0 +1,66 @@
+package assembler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListing(t *testing.T) {
+	fonte := "N EQU 3\n.CODE\nINICIO: LDA X ; carrega\nHLT\n.DATA\nX DB 01, 02, 03, 04, 05\nORG 10\nY DB N\n"
+	esperada := "END   PALAVRAS      LINHA  FONTE\n" +
+		"                        1  N EQU 3\n" +
+		"                        2  .CODE\n" +
+		"00    20 03             3  INICIO: LDA X ; carrega\n" +
+		"02    F0                4  HLT\n" +
+		"                        5  .DATA\n" +
+		"03    01 02 03 04       6  X DB 01, 02, 03, 04, 05\n" +
+		"07    05\n" +
+		"                        7  ORG 10\n" +
+		"10    03                8  Y DB N\n" +
+		"                        9  \n" +
+		"\n" +
+		"TABELA DE SÍMBOLOS\n" +
+		"RÓTULO            END   VALOR\n" +
+		"INICIO            00    20\n" +
+		"X                 03    01\n" +
+		"Y                 10    03\n" +
+		"\n" +
+		"CONSTANTES (EQU)\n" +
+		"NOME              VALOR\n" +
+		"N                 03\n"
+
+	a, err := Assemble(fonte, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listagem := a.Listing(fonte); listagem != esperada {
+		t.Errorf("listagem:\n%s\nesperada:\n%s", listagem, esperada)
+	}
+
+	arquivo := filepath.Join(t.TempDir(), "programa.lst")
+	if err := a.WriteLST(arquivo, fonte); err != nil {
+		t.Fatal(err)
+	}
+	if gravada, err := os.ReadFile(arquivo); err != nil || string(gravada) != esperada {
+		t.Errorf("WriteLST gravou %q, %v", gravada, err)
+	}
+}
+
+// Rótulos no mesmo endereço saem em ordem alfabética.
+func TestListingTabelaSimbolos(t *testing.T) {
+	fonte := ".CODE\nB: A: NOP\nHLT\nFIM:\n"
+	a, err := Assemble(fonte, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperada := "TABELA DE SÍMBOLOS\n" +
+		"RÓTULO            END   VALOR\n" +
+		"A                 00    00\n" +
+		"B                 00    00\n" +
+		"FIM               02    00\n"
+	listagem := a.Listing(fonte)
+	if len(listagem) < len(esperada) || listagem[len(listagem)-len(esperada):] != esperada {
+		t.Errorf("listagem:\n%s\nesperado o fim:\n%s", listagem, esperada)
+	}
+}
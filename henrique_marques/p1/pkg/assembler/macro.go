@@ -0,0 +1,237 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"p1/pkg/assembler/lexer"
+	"p1/pkg/diag"
+)
+
+// limiteExpansao limita o aninhamento de chamadas de macro, para que uma
+// macro que chama a si mesma não trave o assembler.
+const limiteExpansao = 16
+
+// macro é uma definição
+//
+//	MACRO NOME P1, P2
+//	    ...
+//	ENDM
+type macro struct {
+	nome   string
+	params []string
+	corpo  []lexer.Token
+}
+
+// expandeMacros remove as definições MACRO ... ENDM dos tokens e substitui
+// cada chamada pelo corpo da macro, trocando os parâmetros pelos argumentos.
+// Rótulos definidos dentro do corpo são locais: recebem um nome único a
+// cada expansão. Os tokens expandidos ficam com a posição da chamada.
+func expandeMacros(tokens []lexer.Token, erros *diag.Lista) []lexer.Token {
+	macros := map[string]*macro{}
+	var resto []lexer.Token
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Tipo == TOKEN_DEFINE && token.Valor == "ENDM" {
+			erros.Add(token.Pos, "ENDM sem MACRO")
+			continue
+		}
+		if token.Tipo != TOKEN_DEFINE || token.Valor != "MACRO" {
+			resto = append(resto, token)
+			continue
+		}
+		var m *macro
+		m, i = leMacro(tokens, i, erros)
+		if m == nil {
+			continue
+		}
+		if _, existe := macros[m.nome]; existe {
+			erros.Add(token.Pos, "macro já definida: %s", m.nome)
+			continue
+		}
+		macros[m.nome] = m
+	}
+	if len(macros) == 0 {
+		return resto
+	}
+	expansoes := 0
+	return expande(resto, macros, 0, &expansoes, erros)
+}
+
+// leMacro lê a definição que começa no token i e devolve a macro e o índice
+// do ENDM. O nome e os parâmetros ficam na mesma linha do MACRO.
+func leMacro(tokens []lexer.Token, i int, erros *diag.Lista) (*macro, int) {
+	inicio := tokens[i]
+	i++
+	if i >= len(tokens) || tokens[i].Pos.Linha != inicio.Pos.Linha || !isNome(tokens[i]) {
+		erros.Add(inicio.Pos, "esperado nome da macro após MACRO")
+		return nil, fimMacro(tokens, i)
+	}
+	m := &macro{nome: tokens[i].Valor}
+
+	for i+1 < len(tokens) && tokens[i+1].Pos.Linha == inicio.Pos.Linha {
+		i++
+		if len(m.params) > 0 {
+			if tokens[i].Tipo != TOKEN_COMMA {
+				erros.Add(tokens[i].Pos, "esperado ',' entre os parâmetros da macro %s", m.nome)
+				return nil, fimMacro(tokens, i)
+			}
+			i++
+		}
+		if i >= len(tokens) || !isNome(tokens[i]) {
+			erros.Add(tokens[i-1].Pos, "parâmetro inválido na macro %s", m.nome)
+			return nil, fimMacro(tokens, i)
+		}
+		m.params = append(m.params, tokens[i].Valor)
+	}
+
+	for i++; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.Tipo == TOKEN_DEFINE && token.Valor == "ENDM":
+			return m, i
+		case token.Tipo == TOKEN_DEFINE && token.Valor == "MACRO":
+			erros.Add(token.Pos, "MACRO dentro da definição de %s", m.nome)
+		case token.Tipo == TOKEN_EOF:
+			erros.Add(inicio.Pos, "macro %s sem ENDM", m.nome)
+			return nil, i - 1
+		default:
+			m.corpo = append(m.corpo, token)
+		}
+	}
+	erros.Add(inicio.Pos, "macro %s sem ENDM", m.nome)
+	return nil, i
+}
+
+// fimMacro devolve o índice do próximo ENDM, para continuar depois de uma
+// definição inválida.
+func fimMacro(tokens []lexer.Token, i int) int {
+	for ; i < len(tokens); i++ {
+		if tokens[i].Tipo == TOKEN_DEFINE && tokens[i].Valor == "ENDM" {
+			return i
+		}
+		if tokens[i].Tipo == TOKEN_EOF {
+			return i - 1
+		}
+	}
+	return i
+}
+
+func expande(tokens []lexer.Token, macros map[string]*macro, nivel int, expansoes *int, erros *diag.Lista) []lexer.Token {
+	var saida []lexer.Token
+	for i := 0; i < len(tokens); i++ {
+		chamada := tokens[i]
+		m, ok := macros[chamada.Valor]
+		if !ok || !isNome(chamada) {
+			saida = append(saida, chamada)
+			continue
+		}
+		if nivel >= limiteExpansao {
+			erros.Add(chamada.Pos, "expansão da macro %s excede %d níveis (chamada recursiva?)", m.nome, limiteExpansao)
+			continue
+		}
+
+		// Um argumento por parâmetro, separados por vírgula, na linha da
+		// chamada.
+		args := map[string]lexer.Token{}
+		for n, param := range m.params {
+			if n > 0 {
+				if i+1 >= len(tokens) || tokens[i+1].Tipo != TOKEN_COMMA {
+					break
+				}
+				i++
+			}
+			if i+1 >= len(tokens) || !isValorDB(tokens[i+1]) || tokens[i+1].Pos.Linha != chamada.Pos.Linha {
+				break
+			}
+			i++
+			args[param] = tokens[i]
+		}
+		if len(args) != len(m.params) {
+			erros.Add(chamada.Pos, "macro %s espera %d argumento(s)", m.nome, len(m.params))
+			continue
+		}
+
+		*expansoes++
+		locais := rotulosLocais(m)
+		corpo := make([]lexer.Token, 0, len(m.corpo))
+		troca := func(nome string) (string, bool) {
+			if arg, ok := args[nome]; ok {
+				return arg.Valor, true
+			}
+			if locais[nome] {
+				return fmt.Sprintf("_%s_%d_%s", m.nome, *expansoes, nome), true
+			}
+			return nome, false
+		}
+		for _, token := range m.corpo {
+			switch {
+			case isNome(token):
+				if arg, ok := args[token.Valor]; ok {
+					token = arg
+				} else if nome, ok := troca(token.Valor); ok {
+					token.Tipo = TOKEN_VAR
+					token.Valor = nome
+				}
+			case token.Tipo == TOKEN_LABEL:
+				token.Valor, _ = troca(token.Valor)
+			case token.Tipo == TOKEN_EXPR:
+				token.Valor = trocaTermos(token.Valor, troca)
+			}
+			token.Pos = chamada.Pos
+			corpo = append(corpo, token)
+		}
+		saida = append(saida, expande(corpo, macros, nivel+1, expansoes, erros)...)
+	}
+	return saida
+}
+
+// rotulosLocais devolve os rótulos definidos no corpo da macro que não são
+// parâmetros.
+func rotulosLocais(m *macro) map[string]bool {
+	params := map[string]bool{}
+	for _, p := range m.params {
+		params[p] = true
+	}
+	locais := map[string]bool{}
+	for i, token := range m.corpo {
+		if token.Tipo == TOKEN_LABEL && !params[token.Valor] {
+			locais[token.Valor] = true
+			continue
+		}
+		if !isNome(token) || params[token.Valor] || i+1 >= len(m.corpo) {
+			continue
+		}
+		proximo := m.corpo[i+1]
+		if proximo.Tipo == TOKEN_DEFINE && (proximo.Valor == "DB" || proximo.Valor == "DS" || proximo.Valor == "EQU") {
+			locais[token.Valor] = true
+		}
+	}
+	return locais
+}
+
+// trocaTermos aplica troca a cada termo de uma expressão como TABELA+1,
+// mantendo os operadores.
+func trocaTermos(expr string, troca func(string) (string, bool)) string {
+	var sb strings.Builder
+	for expr != "" {
+		fim := strings.IndexAny(expr, "+-")
+		if fim < 0 {
+			fim = len(expr)
+		}
+		termo, _ := troca(expr[:fim])
+		sb.WriteString(termo)
+		if fim < len(expr) {
+			sb.WriteByte(expr[fim])
+			fim++
+		}
+		expr = expr[fim:]
+	}
+	return sb.String()
+}
+
+// isNome informa se o token pode ser um nome de macro, parâmetro ou rótulo.
+// Nomes que também são números hexadecimais (como "CAFE") são aceitos.
+func isNome(token lexer.Token) bool {
+	return token.Tipo == TOKEN_VAR || token.Tipo == TOKEN_NUMBER
+}
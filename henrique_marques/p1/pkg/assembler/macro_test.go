@@ -0,0 +1,36 @@
+package assembler
+
+import "testing"
+
+func TestMacros(t *testing.T) {
+	confere(t, []caso{
+		{
+			nome:     "parâmetros",
+			fonte:    "MACRO SOMA X, Y\nLDA X\nADD Y\nENDM\n.CODE\nSOMA A, B\nHLT\n.DATA\nA DB 01\nB DB 02\n",
+			palavras: []uint8{0x20, 0x05, 0x30, 0x06, 0xF0, 0x01, 0x02},
+		},
+		{
+			nome:     "rótulos locais em duas expansões",
+			fonte:    "MACRO ESPERA\nVOLTA: JZ VOLTA\nENDM\n.CODE\nESPERA\nESPERA\nHLT\n",
+			palavras: []uint8{0xA0, 0x00, 0xA0, 0x02, 0xF0},
+		},
+		{
+			nome:     "dado local e expressão com parâmetro",
+			fonte:    "MACRO LE T\nLDA T+1\nJMP FIM\nK DB 07\nFIM:\nENDM\n.CODE\nLE K\nHLT\n.DATA\nK DB 01, 02\n",
+			palavras: []uint8{0x20, 0x07, 0x80, 0x05, 0x07, 0xF0, 0x01, 0x02},
+		},
+		{
+			nome:     "macro que chama outra",
+			fonte:    "MACRO ZERA V\nLDA Z\nSTA V\nENDM\nMACRO ZERA2 A1, A2\nZERA A1\nZERA A2\nENDM\n.CODE\nZERA2 X, Y\nHLT\n.DATA\nZ DB 0\nX DB 1\nY DB 2\n",
+			palavras: []uint8{0x20, 0x09, 0x10, 0x0A, 0x20, 0x09, 0x10, 0x0B, 0xF0, 0x00, 0x01, 0x02},
+		},
+		{nome: "ENDM sem MACRO", fonte: ".CODE\nHLT\nENDM\n", erro: "ENDM sem MACRO"},
+		{nome: "MACRO sem ENDM", fonte: "MACRO M\nNOP\n.CODE\nHLT\n", erro: "macro M sem ENDM"},
+		{nome: "MACRO sem nome", fonte: "MACRO\nNOP\nENDM\n", erro: "esperado nome da macro após MACRO"},
+		{nome: "parâmetros sem vírgula", fonte: "MACRO M A B\nNOP\nENDM\n", erro: "esperado ',' entre os parâmetros da macro M"},
+		{nome: "macro redefinida", fonte: "MACRO M\nNOP\nENDM\nMACRO M\nHLT\nENDM\n", erro: "macro já definida: M"},
+		{nome: "MACRO aninhada", fonte: "MACRO M\nMACRO N\nENDM\n", erro: "MACRO dentro da definição de M"},
+		{nome: "argumentos a menos", fonte: "MACRO M A, B\nLDA A\nENDM\n.CODE\nM 01\n", erro: "macro M espera 2 argumento(s)"},
+		{nome: "recursão", fonte: "MACRO M\nM\nENDM\n.CODE\nM\n", erro: "expansão da macro M excede 16 níveis"},
+	})
+}
@@ -0,0 +1,76 @@
+package assembler
+
+import (
+	"sort"
+	"strings"
+
+	"p1/pkg/assembler/lexer"
+	"p1/pkg/simbolos"
+)
+
+type rotulo struct {
+	linha int
+	dado  bool
+}
+
+// TabelaSimbolos monta a tabela de símbolos do programa: o endereço de cada
+// rótulo, as constantes EQU e, para cada palavra gravada, a linha do .asm
+// que a gerou. Se o assembly veio do compilador, as marcas "; @linha"
+// ligam cada endereço também à linha do .ldh. Deve ser chamada depois da
+// SecondPass.
+func (a *Assembler) TabelaSimbolos(fonte string) *simbolos.Tabela {
+	linhas := strings.Split(strings.ReplaceAll(fonte, "\r\n", "\n"), "\n")
+	tabela := &simbolos.Tabela{
+		Simbolos: []simbolos.Simbolo{},
+		Linhas:   []simbolos.Linha{},
+	}
+
+	for nome, addr := range a.Labels {
+		s := simbolos.Simbolo{Nome: nome, Endereco: addr, Tipo: simbolos.TipoCodigo}
+		if r, ok := a.rotulos[nome]; ok {
+			s.Linha = r.linha
+			if r.dado {
+				s.Tipo = simbolos.TipoDado
+			}
+			if r.linha <= len(linhas) {
+				s.Descricao = lexer.Comentario(linhas[r.linha-1])
+			}
+		}
+		tabela.Simbolos = append(tabela.Simbolos, s)
+	}
+	sort.Slice(tabela.Simbolos, func(i, j int) bool {
+		si, sj := tabela.Simbolos[i], tabela.Simbolos[j]
+		if si.Endereco != sj.Endereco {
+			return si.Endereco < sj.Endereco
+		}
+		return si.Nome < sj.Nome
+	})
+
+	if len(a.Constantes) > 0 {
+		tabela.Constantes = a.Constantes
+	}
+
+	origem := 0
+	for i, linha := range linhas {
+		if n, ok := simbolos.LinhaDaMarca(linha); ok {
+			origem = n
+			continue
+		}
+		// As marcas descrevem apenas o código; uma nova seção as encerra.
+		if strings.HasPrefix(strings.TrimSpace(linha), ".") {
+			origem = 0
+		}
+		for _, p := range a.emitidas[i+1] {
+			tabela.Linhas = append(tabela.Linhas, simbolos.Linha{Endereco: p.addr, Linha: i + 1, Origem: origem})
+		}
+	}
+	sort.SliceStable(tabela.Linhas, func(i, j int) bool {
+		return tabela.Linhas[i].Endereco < tabela.Linhas[j].Endereco
+	})
+	return tabela
+}
+
+// WriteSYM grava a tabela de símbolos do programa no arquivo .sym, em JSON.
+func (a *Assembler) WriteSYM(filename string, fonte string) error {
+	return a.TabelaSimbolos(fonte).Grava(filename)
+}
@@ -0,0 +1,139 @@
+package ast
+
+import "p1/pkg/diag"
+
+// Node é qualquer nó da árvore sintática de um programa LDH.
+type Node interface {
+	Posicao() diag.Pos
+}
+
+// Expr é um nó que produz um valor de 8 bits.
+type Expr interface {
+	Node
+	expr()
+}
+
+// Stmt é uma instrução do corpo do programa.
+type Stmt interface {
+	Node
+	stmt()
+}
+
+// Todos os nós guardam em Pos a posição do token que os originou.
+
+// Program é o programa inteiro; Fim é a posição do FIM. Vars são as
+// variáveis do bloco VARIAVEIS, na ordem em que aparecem; sem o bloco, as
+// variáveis são declaradas pelo uso.
+type Program struct {
+	Pos  diag.Pos
+	Fim  diag.Pos
+	Name string
+	Vars []*VarDecl
+	Body []Stmt
+}
+
+// VarDecl declara uma variável em "VARIAVEIS A, B = 5". Init é nil se a
+// variável não tiver valor inicial (começa com 0).
+type VarDecl struct {
+	Pos  diag.Pos
+	Name string
+	Init *Literal
+}
+
+// Assign representa "Var = Value".
+type Assign struct {
+	Pos   diag.Pos
+	Var   *Ident
+	Value Expr
+}
+
+// If representa "SE Cond ENTAO Then [SENAO Else] FIMSE".
+type If struct {
+	Pos  diag.Pos
+	Cond Expr
+	Then []Stmt
+	Else []Stmt
+}
+
+// While representa "ENQUANTO Cond FACA Body FIMENQUANTO".
+type While struct {
+	Pos  diag.Pos
+	Cond Expr
+	Body []Stmt
+}
+
+// Read representa "LEIA Var": Var recebe o próximo valor da entrada. Pos é
+// a posição do LEIA e Var.Pos, a da variável.
+type Read struct {
+	Pos diag.Pos
+	Var *Ident
+}
+
+// Write representa "ESCREVA Value": o valor vai para a saída.
+type Write struct {
+	Pos   diag.Pos
+	Value Expr
+}
+
+// BinaryExpr é uma operação aritmética (+, -, *, /, %), bit a bit (&, |),
+// um deslocamento (<<, >>) ou, nas condições, uma comparação (<, >, ==, !=,
+// <=, >=). Pos é a posição do operador.
+type BinaryExpr struct {
+	Pos   diag.Pos
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// UnaryExpr é a negação em complemento de dois (-) ou a inversão dos bits
+// (~) do operando.
+type UnaryExpr struct {
+	Pos     diag.Pos
+	Op      string
+	Operand Expr
+}
+
+// Literal guarda em Value o número como escrito no código-fonte (10, 0x0A,
+// 'A', ...) e em Number o seu valor.
+type Literal struct {
+	Pos    diag.Pos
+	Value  string
+	Number uint8
+}
+
+type Ident struct {
+	Pos  diag.Pos
+	Name string
+}
+
+func (n *Program) Posicao() diag.Pos    { return n.Pos }
+func (n *VarDecl) Posicao() diag.Pos    { return n.Pos }
+func (n *Assign) Posicao() diag.Pos     { return n.Pos }
+func (n *If) Posicao() diag.Pos         { return n.Pos }
+func (n *While) Posicao() diag.Pos      { return n.Pos }
+func (n *Read) Posicao() diag.Pos       { return n.Pos }
+func (n *Write) Posicao() diag.Pos      { return n.Pos }
+func (n *BinaryExpr) Posicao() diag.Pos { return n.Pos }
+func (n *UnaryExpr) Posicao() diag.Pos  { return n.Pos }
+func (n *Literal) Posicao() diag.Pos    { return n.Pos }
+func (n *Ident) Posicao() diag.Pos      { return n.Pos }
+
+func (*Assign) stmt() {}
+func (*If) stmt()     {}
+func (*While) stmt()  {}
+func (*Read) stmt()   {}
+func (*Write) stmt()  {}
+
+func (*BinaryExpr) expr() {}
+func (*UnaryExpr) expr()  {}
+func (*Literal) expr()    {}
+func (*Ident) expr()      {}
+
+// IsRelational informa se op é um operador relacional.
+func IsRelational(op string) bool {
+	switch op {
+	case "<", ">", "==", "!=", "<=", ">=":
+		return true
+	}
+	return false
+}
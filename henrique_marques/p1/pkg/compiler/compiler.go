@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"fmt"
+
+	"p1/pkg/compiler/generator"
+	"p1/pkg/compiler/lexer"
+	"p1/pkg/compiler/parser"
+	"p1/pkg/compiler/semantic"
+	"p1/pkg/diag"
+)
+
+// Compile traduz um programa .ldh para o assembly do Neander. Os erros
+// léxicos e sintáticos são reportados juntos em uma diag.Lista, e depois
+// deles os da análise semântica (veja semantic.Analisa). Com otimiza, as
+// expressões constantes são calculadas em tempo de compilação e o assembly
+// gerado passa pelo generator.Otimiza. Os avisos são descartados; use
+// CompileASM para obtê-los.
+func Compile(fonte string, otimiza bool) (string, error) {
+	prog, _, err := CompileASM(fonte, otimiza)
+	if err != nil {
+		return "", err
+	}
+	return prog.String(), nil
+}
+
+// CompileASM é como Compile, mas devolve o programa com o código e os dados
+// separados, para que o uso de memória possa ser consultado, e os avisos
+// da análise semântica, mesmo quando há erros. Os
+// temporários que não estão vivos ao mesmo tempo dividem a mesma posição
+// (veja generator.AlocaTemporarios), e um programa que não cabe nas 256
+// palavras do Neander é um erro.
+func CompileASM(fonte string, otimiza bool) (generator.ASMProgram, diag.Lista, error) {
+	tokens, errLex := lexer.Lex(fonte)
+	programa, errParse := parser.NewParser(tokens).ParsePrograma()
+	if err := diag.Junta(errLex, errParse); err != nil {
+		return generator.ASMProgram{}, nil, err
+	}
+	avisos, err := semantic.Analisa(programa)
+	if err != nil {
+		return generator.ASMProgram{}, avisos, err
+	}
+
+	if otimiza {
+		generator.DobraConstantes(programa)
+	}
+	prog, err := generator.GenerateASM(programa)
+	if err != nil {
+		return generator.ASMProgram{}, avisos, err
+	}
+	if otimiza {
+		generator.Otimiza(&prog)
+	}
+	generator.AlocaTemporarios(&prog)
+
+	if uso := prog.Memoria(); uso.Total() > generator.TamanhoMemoria {
+		return prog, avisos, fmt.Errorf("o programa não cabe na memória: %d palavras (%d de código e %d de dados), máximo %d",
+			uso.Total(), uso.Codigo, uso.Dados(), generator.TamanhoMemoria)
+	}
+	return prog, avisos, nil
+}
@@ -0,0 +1,93 @@
+package compiler
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"p1/pkg/assembler"
+)
+
+// fontesDeExemplo são o programa de exemplo do repositório e os programas
+// .ldh do harness de testes (pkg/golden/testdata).
+func fontesDeExemplo(t *testing.T) []string {
+	arquivos, err := filepath.Glob("../golden/testdata/*.ldh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]string{"../../io/linguagemCriada/program.ldh"}, arquivos...)
+}
+
+func monta(t *testing.T, fonte string, otimiza bool) (string, []byte) {
+	t.Helper()
+	asm, err := Compile(fonte, otimiza)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asmb, err := assembler.Assemble(asm, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var imagem bytes.Buffer
+	if err := asmb.EscreveMEM(&imagem); err != nil {
+		t.Fatal(err)
+	}
+	return asm, imagem.Bytes()
+}
+
+// TestCompilacaoDeterministica compila cada programa várias vezes e exige
+// o mesmo assembly e o mesmo .mem byte a byte. A ordem de iteração de um
+// map muda a cada execução, então uma só repetição não bastaria.
+func TestCompilacaoDeterministica(t *testing.T) {
+	for _, arquivo := range fontesDeExemplo(t) {
+		fonte, err := os.ReadFile(arquivo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, otimiza := range []bool{false, true} {
+			asm, imagem := monta(t, string(fonte), otimiza)
+			for i := 0; i < 20; i++ {
+				outroAsm, outraImagem := monta(t, string(fonte), otimiza)
+				if outroAsm != asm {
+					t.Fatalf("%s (-O=%v): assembly diferente na compilação %d:\n%s\n---\n%s", arquivo, otimiza, i+2, asm, outroAsm)
+				}
+				if !bytes.Equal(outraImagem, imagem) {
+					t.Fatalf("%s (-O=%v): .mem diferente na compilação %d", arquivo, otimiza, i+2)
+				}
+			}
+		}
+	}
+}
+
+// TestLayout confere os endereços de -emit-layout com os que o assembler
+// deu aos rótulos.
+func TestLayout(t *testing.T) {
+	for _, arquivo := range fontesDeExemplo(t) {
+		fonte, err := os.ReadFile(arquivo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, _, err := CompileASM(string(fonte), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		asmb, err := assembler.Assemble(prog.String(), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layout := prog.Layout()
+		if len(layout) == 0 {
+			t.Errorf("%s: layout vazio", arquivo)
+		}
+		for _, c := range layout {
+			addr, ok := asmb.Labels[c.Nome]
+			if c.Tipo == "E/S" {
+				addr, ok = asmb.Constantes[c.Nome]
+			}
+			if !ok || int(addr) != c.Endereco {
+				t.Errorf("%s: %s no layout em %02X, no assembler em %02X", arquivo, c.Nome, c.Endereco, addr)
+			}
+		}
+	}
+}
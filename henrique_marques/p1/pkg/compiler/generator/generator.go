@@ -2,9 +2,13 @@ package generator
 
 import (
 	"fmt"
-	"p1/pkg/compiler/lexer"
-	"p1/pkg/compiler/parser"
+	asmlexer "p1/pkg/assembler/lexer"
+	"p1/pkg/compiler/ast"
+	"p1/pkg/diag"
+	"p1/pkg/encoder"
+	"p1/pkg/simbolos"
 	"strconv"
+	"strings"
 )
 
 type ASMProgram struct {
@@ -12,12 +16,22 @@ type ASMProgram struct {
 	Data []string
 }
 
+// maxDesenrolado é o maior multiplicador constante para o qual a
+// multiplicação é gerada como uma sequência de ADDs em vez de um laço.
+const maxDesenrolado = 8
+
 var tmpCount = 0
+var labelCount = 0
 var constSet = map[string]bool{}
+var usaDiv = false
+var erros diag.Lista
 
 func resetState() {
 	tmpCount = 0
+	labelCount = 0
 	constSet = map[string]bool{}
+	usaDiv = false
+	erros = nil
 }
 
 func newTmp() string {
@@ -26,104 +40,558 @@ func newTmp() string {
 	return tmp
 }
 
-func GenerateASM(instrucoes []parser.Instrucao) ASMProgram {
+// newLabel gera um rótulo de código. O '_' garante que não colida com
+// variáveis do programa, que só têm letras e dígitos.
+func newLabel(prefixo string) string {
+	label := fmt.Sprintf("%s_%d", prefixo, labelCount)
+	labelCount++
+	return label
+}
+
+// addTmp declara um novo temporário. A descrição vai no comentário da
+// declaração e chega à tabela de símbolos do assembler.
+func addTmp(prog *ASMProgram, descricao string) string {
+	tmp := newTmp()
+	prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00 ; %s", tmp, descricao))
+	return tmp
+}
+
+// addConst declara, uma única vez, a constante com o valor dado. O nome
+// depende só do valor, então 10, 0x0A e 0b1010 usam a mesma posição. O
+// valor é escrito com asmlexer.Hex, porque 219 em hexadecimal é DB.
+func addConst(prog *ASMProgram, valor uint8) string {
+	constLabel := fmt.Sprintf("CONST_%02X", valor)
+	if !constSet[constLabel] {
+		prog.Data = append(prog.Data, fmt.Sprintf("%s DB %s", constLabel, asmlexer.Hex(valor)))
+		constSet[constLabel] = true
+	}
+	return constLabel
+}
+
+// Rótulos dos endereços de entrada e saída do emulador, usados por LEIA e
+// ESCREVA. O '_' evita colisão com as variáveis do programa.
+const (
+	EntradaES = "ES_ENTRADA"
+	SaidaES   = "ES_SAIDA"
+)
+
+// addES declara, uma única vez, o rótulo de um endereço de E/S.
+func addES(prog *ASMProgram, rotulo string) string {
+	if !constSet[rotulo] {
+		endereco, descricao := encoder.IO_IN, "entrada (LEIA)"
+		if rotulo == SaidaES {
+			endereco, descricao = encoder.IO_OUT, "saída (ESCREVA)"
+		}
+		prog.Data = append(prog.Data, fmt.Sprintf("%s EQU %02X ; %s", rotulo, endereco, descricao))
+		constSet[rotulo] = true
+	}
+	return rotulo
+}
+
+// GenerateASM gera o assembly do programa. Construções que o gerador não
+// sabe traduzir são reportadas com sua posição em uma diag.Lista.
+func GenerateASM(programa *ast.Program) (ASMProgram, error) {
 	resetState()
 	prog := ASMProgram{
 		Code: []string{".CODE", "ORG 00"},
-		Data: []string{".DATA", "ORG 20"},
+		Data: []string{".DATA"},
+	}
+
+	vars := &variaveis{vistas: map[string]bool{}}
+
+	genInstrucoes(&prog, programa.Body, vars)
+
+	// As variáveis declaradas vêm primeiro, na ordem da declaração e com
+	// o valor inicial.
+	for _, d := range programa.Vars {
+		var valor uint8
+		if d.Init != nil {
+			valor = d.Init.Number
+		}
+		prog.Data = append(prog.Data, fmt.Sprintf("%s DB %s", d.Name, asmlexer.Hex(valor)))
+	}
+
+	// As demais, na ordem em que aparecem no código gerado.
+	for _, v := range vars.ordem {
+		if !declarada(programa, v) {
+			prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", v))
+		}
+	}
+
+	prog.Code = append(prog.Code, simbolos.Marca(programa.Fim.Linha), "HLT")
+	if usaDiv {
+		genRotinaDiv(&prog)
 	}
 
-	varsUsadas := map[string]bool{}
+	// Os rótulos de desvio ("NOME:") são resolvidos pelo assembler, que
+	// também põe a seção de dados logo após o código.
+	return prog, erros.Err()
+}
+
+// variaveis guarda as variáveis do programa na ordem em que aparecem no
+// código gerado. É essa ordem que define o endereço de cada uma, então ela
+// não pode depender da iteração de um map: o mesmo fonte deve gerar sempre
+// o mesmo .mem.
+type variaveis struct {
+	vistas map[string]bool
+	ordem  []string
+}
 
+func (v *variaveis) usa(nome string) {
+	if !v.vistas[nome] {
+		v.vistas[nome] = true
+		v.ordem = append(v.ordem, nome)
+	}
+}
+
+func declarada(programa *ast.Program, nome string) bool {
+	for _, d := range programa.Vars {
+		if d.Name == nome {
+			return true
+		}
+	}
+	return false
+}
+
+func genInstrucoes(prog *ASMProgram, instrucoes []ast.Stmt, vars *variaveis) {
 	for _, inst := range instrucoes {
-		stack := []string{}
-		for _, tok := range inst.Expr {
-			switch tok.Tipo {
-			case lexer.TOKEN_NUM:
-				constLabel := "CONST_" + tok.Valor
-				if !constSet[constLabel] {
-					prog.Data = append(prog.Data, fmt.Sprintf("%s DB %s", constLabel, tok.Valor))
-					constSet[constLabel] = true
-				}
-				stack = append(stack, constLabel)
-
-			case lexer.TOKEN_VAR:
-				varsUsadas[tok.Valor] = true
-				stack = append(stack, tok.Valor)
-
-			case lexer.TOKEN_OP:
-				if len(stack) < 2 {
-					panic("expressão mal formada")
-				}
-				right := stack[len(stack)-1]
-				left := stack[len(stack)-2]
-				stack = stack[:len(stack)-2]
-
-				tmp := newTmp()
-				prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", tmp))
-
-				if tok.Valor == "*" {
-					prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
-					value := 0
-					if valStr := right; len(valStr) > 6 && valStr[:6] == "CONST_" {
-						hex := valStr[6:]
-						parsed, err := strconv.ParseUint(hex, 16, 8)
-						if err == nil {
-							value = int(parsed)
-						}
-					}
-					for i := 1; i < value; i++ {
-						prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", left))
-					}
-				} else {
-					switch tok.Valor {
-					case "+":
-						prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
-						prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", right))
-					case "-":
-						negTmp := newTmp()
-						prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", negTmp))
-						
-						prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", right))
-						prog.Code = append(prog.Code, "NOT")
-						prog.Code = append(prog.Code, "ADD CONST_01")
-						prog.Code = append(prog.Code, fmt.Sprintf("STA %s", negTmp))
-						
-						prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
-						prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", negTmp))
-						
-						if !constSet["CONST_01"] {
-							prog.Data = append(prog.Data, "CONST_01 DB 01")
-							constSet["CONST_01"] = true
-						}
-					case "/":
-						prog.Code = append(prog.Code, "; DIV não suportado")
-					}
-				}
-				prog.Code = append(prog.Code, fmt.Sprintf("STA %s", tmp))
-				stack = append(stack, tmp)
-				
+		// A marca liga o código gerado à linha da instrução no .ldh.
+		prog.Code = append(prog.Code, simbolos.Marca(inst.Posicao().Linha))
+		switch inst := inst.(type) {
+		case *ast.If:
+			senao := newLabel("SENAO")
+			fimSe := newLabel("FIMSE")
+			genSaltoSeFalso(prog, inst.Cond, senao, vars)
+			genInstrucoes(prog, inst.Then, vars)
+			if len(inst.Else) > 0 {
+				prog.Code = append(prog.Code, simbolos.Marca(inst.Pos.Linha), fmt.Sprintf("JMP %s", fimSe))
 			}
+			prog.Code = append(prog.Code, senao+":")
+			if len(inst.Else) > 0 {
+				genInstrucoes(prog, inst.Else, vars)
+				prog.Code = append(prog.Code, fimSe+":")
+			}
+		case *ast.While:
+			inicio := newLabel("ENQUANTO")
+			fim := newLabel("FIMENQUANTO")
+			prog.Code = append(prog.Code, inicio+":")
+			genSaltoSeFalso(prog, inst.Cond, fim, vars)
+			genInstrucoes(prog, inst.Body, vars)
+			prog.Code = append(prog.Code, simbolos.Marca(inst.Pos.Linha), fmt.Sprintf("JMP %s", inicio), fim+":")
+		case *ast.Assign:
+			result := genExpr(prog, inst.Value, vars)
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", result))
+			prog.Code = append(prog.Code, fmt.Sprintf("STA %s", inst.Var.Name))
+			vars.usa(inst.Var.Name)
+		case *ast.Read:
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", addES(prog, EntradaES)))
+			prog.Code = append(prog.Code, fmt.Sprintf("STA %s", inst.Var.Name))
+			vars.usa(inst.Var.Name)
+		case *ast.Write:
+			result := genExpr(prog, inst.Value, vars)
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", result))
+			prog.Code = append(prog.Code, fmt.Sprintf("STA %s", addES(prog, SaidaES)))
+		default:
+			erros.Add(inst.Posicao(), "instrução não suportada pelo gerador: %T", inst)
 		}
+	}
+}
 
-		if len(stack) != 1 {
-			panic("erro interno: pilha final da expressão não tem 1 item")
+// genExpr gera o código da expressão e retorna o rótulo da posição de
+// memória que guarda o resultado.
+func genExpr(prog *ASMProgram, expr ast.Expr, vars *variaveis) string {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		return addConst(prog, e.Number)
+
+	case *ast.Ident:
+		vars.usa(e.Name)
+		return e.Name
+
+	case *ast.UnaryExpr:
+		operand := genExpr(prog, e.Operand, vars)
+		tmp := addTmp(prog, texto(e))
+
+		prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", operand))
+		prog.Code = append(prog.Code, "NOT")
+		switch e.Op {
+		case "~":
+		case "-":
+			prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", addConst(prog, 1)))
+		default:
+			erros.Add(e.Pos, "operador unário '%s' não pode ser usado em expressões", e.Op)
+		}
+		prog.Code = append(prog.Code, fmt.Sprintf("STA %s", tmp))
+		return tmp
+
+	case *ast.BinaryExpr:
+		left := genExpr(prog, e.Left, vars)
+		right := genExpr(prog, e.Right, vars)
+
+		tmp := addTmp(prog, texto(e))
+
+		switch e.Op {
+		case "+":
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+			prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", right))
+		case "-":
+			genSub(prog, left, right, "-"+operando(e.Right))
+		case "*":
+			genMul(prog, left, right, tmp, texto(e))
+		case "/", "%":
+			genDiv(prog, left, right, e.Op == "%")
+		case "&":
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+			prog.Code = append(prog.Code, fmt.Sprintf("AND %s", right))
+		case "|":
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+			prog.Code = append(prog.Code, fmt.Sprintf("OR %s", right))
+		case "<<":
+			genShl(prog, left, right, tmp, texto(e))
+		case ">>":
+			genShr(prog, left, right, texto(e))
+		default:
+			erros.Add(e.Pos, "operador '%s' não pode ser usado em expressões", e.Op)
 		}
-		prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", stack[0]))
-		prog.Code = append(prog.Code, fmt.Sprintf("STA %s", inst.Var))
+		prog.Code = append(prog.Code, fmt.Sprintf("STA %s", tmp))
+		return tmp
 	}
+	erros.Add(expr.Posicao(), "expressão não suportada pelo gerador: %T", expr)
+	return addConst(prog, 0)
+}
+
+// genSub deixa left - right no AC, somando o complemento de dois de right.
+// O complemento fica em um temporário descrito por descricao.
+func genSub(prog *ASMProgram, left, right, descricao string) {
+	negTmp := addTmp(prog, descricao)
 
-	for v := range varsUsadas {
-		prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", v))
+	prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", right))
+	prog.Code = append(prog.Code, "NOT")
+	prog.Code = append(prog.Code, "ADD CONST_01")
+	prog.Code = append(prog.Code, fmt.Sprintf("STA %s", negTmp))
+
+	prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+	prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", negTmp))
+
+	addConst(prog, 1)
+}
+
+// genSaltoSeFalso gera a comparação da condição e um desvio para destino
+// quando ela é falsa. Os valores são comparados sem sinal (veja genMenor);
+// == e != usam o zero (JZ) da diferença entre os lados.
+func genSaltoSeFalso(prog *ASMProgram, expr ast.Expr, destino string, vars *variaveis) {
+	cond, ok := expr.(*ast.BinaryExpr)
+	if !ok || !ast.IsRelational(cond.Op) {
+		erros.Add(expr.Posicao(), "condição sem operador relacional")
+		return
 	}
+	esq := genExpr(prog, cond.Left, vars)
+	dir := genExpr(prog, cond.Right, vars)
 
-	for _, inst := range instrucoes {
-		if !varsUsadas[inst.Var] {
-			prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", inst.Var))
-			varsUsadas[inst.Var] = true
+	// a > b e a <= b são avaliados como b < a e b >= a.
+	exprDir := cond.Right
+	if cond.Op == ">" || cond.Op == "<=" {
+		esq, dir = dir, esq
+		exprDir = cond.Left
+	}
+
+	switch cond.Op {
+	case "<", ">":
+		verdadeiro := newLabel("VERDADEIRO")
+		genMenor(prog, esq, dir, "-"+operando(exprDir), verdadeiro, destino)
+		prog.Code = append(prog.Code,
+			fmt.Sprintf("JMP %s", destino),
+			verdadeiro+":",
+		)
+	case ">=", "<=":
+		verdadeiro := newLabel("VERDADEIRO")
+		genMenor(prog, esq, dir, "-"+operando(exprDir), destino, verdadeiro)
+		prog.Code = append(prog.Code, verdadeiro+":")
+	case "==":
+		genSub(prog, esq, dir, "-"+operando(exprDir))
+		verdadeiro := newLabel("VERDADEIRO")
+		prog.Code = append(prog.Code,
+			fmt.Sprintf("JZ %s", verdadeiro),
+			fmt.Sprintf("JMP %s", destino),
+			verdadeiro+":",
+		)
+	case "!=":
+		genSub(prog, esq, dir, "-"+operando(exprDir))
+		prog.Code = append(prog.Code, fmt.Sprintf("JZ %s", destino))
+	}
+}
+
+// genMenor desvia para menor se esq < dir, comparando sem sinal, e para
+// naoMenor caso contrário; o último desvio para naoMenor fica a cargo de
+// quem chama. Com os dois valores na mesma metade (o mesmo bit 7), a
+// diferença cabe em 8 bits com sinal e o JN dela decide. Em metades
+// diferentes, o menor é o que tem o bit 7 desligado. A metade de uma
+// constante já é conhecida e dispensa o teste.
+func genMenor(prog *ASMProgram, esq, dir, descricao, menor, naoMenor string) {
+	code := func(linhas ...string) {
+		prog.Code = append(prog.Code, linhas...)
+	}
+	e, d := metade(esq), metade(dir)
+	mesma := newLabel("MESMA_METADE")
+	switch {
+	case e >= 0 && d >= 0 && e != d:
+		if e == 0 {
+			code("JMP " + menor)
+		} else {
+			code("JMP " + naoMenor)
+		}
+		return
+	case e < 0:
+		esqAlto := newLabel("ESQ_ALTO")
+		code("LDA "+esq, "JN "+esqAlto)
+		switch d {
+		case -1:
+			code("LDA "+dir, "JN "+menor, "JMP "+mesma)
+		case 0:
+			code("JMP " + mesma)
+		case 1:
+			code("JMP " + menor)
+		}
+		code(esqAlto + ":")
+		switch d {
+		case -1:
+			code("LDA "+dir, "JN "+mesma, "JMP "+naoMenor)
+		case 0:
+			code("JMP " + naoMenor)
 		}
+	case d < 0 && e == 0:
+		code("LDA "+dir, "JN "+menor)
+	case d < 0 && e == 1:
+		code("LDA "+dir, "JN "+mesma, "JMP "+naoMenor)
 	}
+	code(mesma + ":")
+	genSub(prog, esq, dir, descricao)
+	code("JN " + menor)
+}
 
-	prog.Code = append(prog.Code, "HLT")
-	return prog
+// metade devolve o bit 7 do valor da posição, se ela for uma constante,
+// ou -1 se o valor só é conhecido durante a execução.
+func metade(rotulo string) int {
+	if !strings.HasPrefix(rotulo, "CONST_") {
+		return -1
+	}
+	valor, err := strconv.ParseUint(rotulo[6:], 16, 8)
+	if err != nil {
+		return -1
+	}
+	return int(valor >> 7)
+}
+
+// genMul gera left * right, deixando o resultado no AC. Multiplicadores
+// constantes pequenos viram uma sequência de ADDs; os demais casos usam um
+// laço de somas sucessivas controlado por um contador. descricao é o texto
+// da multiplicação, para o comentário do contador.
+func genMul(prog *ASMProgram, left, right, tmp, descricao string) {
+	if strings.HasPrefix(right, "CONST_") {
+		if value, err := strconv.ParseUint(right[6:], 16, 8); err == nil && value <= maxDesenrolado {
+			if value == 0 {
+				prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", addConst(prog, 0)))
+				return
+			}
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+			for i := uint64(1); i < value; i++ {
+				prog.Code = append(prog.Code, fmt.Sprintf("ADD %s", left))
+			}
+			return
+		}
+	}
+
+	zero := addConst(prog, 0)
+	menosUm := addConst(prog, 0xFF)
+	cont := addTmp(prog, "contador de "+descricao)
+	laco := newLabel("MUL")
+	fim := newLabel("MUL_FIM")
+
+	prog.Code = append(prog.Code,
+		fmt.Sprintf("LDA %s", zero),
+		fmt.Sprintf("STA %s", tmp),
+		fmt.Sprintf("LDA %s", right),
+		fmt.Sprintf("STA %s", cont),
+		laco+":",
+		fmt.Sprintf("LDA %s", cont),
+		fmt.Sprintf("JZ %s", fim),
+		fmt.Sprintf("LDA %s", tmp),
+		fmt.Sprintf("ADD %s", left),
+		fmt.Sprintf("STA %s", tmp),
+		fmt.Sprintf("LDA %s", cont),
+		fmt.Sprintf("ADD %s", menosUm),
+		fmt.Sprintf("STA %s", cont),
+		fmt.Sprintf("JMP %s", laco),
+		fim+":",
+		fmt.Sprintf("LDA %s", tmp),
+	)
+}
+
+// genShl gera left << right, deixando o resultado no AC. Cada deslocamento
+// dobra o valor somando-o a si mesmo (STA tmp, ADD tmp). Deslocamentos
+// constantes são desenrolados; os demais usam um laço controlado por um
+// contador, como a multiplicação.
+func genShl(prog *ASMProgram, left, right, tmp, descricao string) {
+	if strings.HasPrefix(right, "CONST_") {
+		if value, err := strconv.ParseUint(right[6:], 16, 8); err == nil {
+			if value >= 8 {
+				prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", addConst(prog, 0)))
+				return
+			}
+			prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", left))
+			for i := uint64(0); i < value; i++ {
+				prog.Code = append(prog.Code, fmt.Sprintf("STA %s", tmp), fmt.Sprintf("ADD %s", tmp))
+			}
+			return
+		}
+	}
+
+	menosUm := addConst(prog, 0xFF)
+	cont := addTmp(prog, "contador de "+descricao)
+	laco := newLabel("DESLOCA")
+	fim := newLabel("DESLOCA_FIM")
+
+	prog.Code = append(prog.Code,
+		fmt.Sprintf("LDA %s", left),
+		fmt.Sprintf("STA %s", tmp),
+		fmt.Sprintf("LDA %s", right),
+		fmt.Sprintf("STA %s", cont),
+		laco+":",
+		fmt.Sprintf("LDA %s", cont),
+		fmt.Sprintf("JZ %s", fim),
+		fmt.Sprintf("LDA %s", tmp),
+		fmt.Sprintf("ADD %s", tmp),
+		fmt.Sprintf("STA %s", tmp),
+		fmt.Sprintf("LDA %s", cont),
+		fmt.Sprintf("ADD %s", menosUm),
+		fmt.Sprintf("STA %s", cont),
+		fmt.Sprintf("JMP %s", laco),
+		fim+":",
+		fmt.Sprintf("LDA %s", tmp),
+	)
+}
+
+// genShr gera left >> right, deixando o resultado no AC. O Neander não
+// desloca para a direita, então o deslocamento é a divisão de left por
+// 2^right: uma constante, se right for constante, ou calculada com genShl.
+// Como 1 << right é zero a partir de 8, a divisão por zero dá o quociente 0
+// esperado.
+func genShr(prog *ASMProgram, left, right, descricao string) {
+	if strings.HasPrefix(right, "CONST_") {
+		if value, err := strconv.ParseUint(right[6:], 16, 8); err == nil {
+			if value >= 8 {
+				prog.Code = append(prog.Code, fmt.Sprintf("LDA %s", addConst(prog, 0)))
+				return
+			}
+			genDiv(prog, left, addConst(prog, 1<<value), false)
+			return
+		}
+	}
+
+	divisor := addTmp(prog, "divisor de "+descricao)
+	genShl(prog, addConst(prog, 1), right, divisor, descricao)
+	prog.Code = append(prog.Code, fmt.Sprintf("STA %s", divisor))
+	genDiv(prog, left, divisor, false)
+}
+
+// genDiv gera uma chamada à rotina de divisão inteira sem sinal, deixando
+// no AC o quociente ou, se resto for verdadeiro, o resto. O Neander não tem
+// instrução de chamada: antes do JMP, o endereço de retorno é gravado no
+// operando do JMP final da rotina.
+func genDiv(prog *ASMProgram, left, right string, resto bool) {
+	usaDiv = true
+	retorno := newLabel("RETORNO")
+	ptr := "PTR_" + retorno
+	prog.Data = append(prog.Data, fmt.Sprintf("%s DB %s", ptr, retorno))
+
+	prog.Code = append(prog.Code,
+		fmt.Sprintf("LDA %s", left),
+		"STA DIV_A",
+		fmt.Sprintf("LDA %s", right),
+		"STA DIV_B",
+		fmt.Sprintf("LDA %s", ptr),
+		"STA DIV_VOLTA+1",
+		"JMP DIV_INICIO",
+		retorno+":",
+	)
+	if resto {
+		prog.Code = append(prog.Code, "LDA DIV_R")
+	} else {
+		prog.Code = append(prog.Code, "LDA DIV_Q")
+	}
+}
+
+// genRotinaDiv gera a rotina compartilhada por "/" e "%", que divide DIV_A
+// por DIV_B. Como o Neander não tem subtração nem flag de carry, cada
+// subtração do divisor é feita decrementando o resto e uma cópia do divisor
+// até um deles zerar: se o resto zerar antes, ele era menor que o divisor e
+// é restaurado. Divisão por zero resulta em quociente 0 e resto DIV_A.
+func genRotinaDiv(prog *ASMProgram) {
+	prog.Code = append(prog.Code, simbolos.Marca(0))
+	zero := addConst(prog, 0)
+	um := addConst(prog, 1)
+	menosUm := addConst(prog, 0xFF)
+	for _, cel := range []string{"DIV_A", "DIV_B", "DIV_Q", "DIV_R", "DIV_CONT", "DIV_SALVO"} {
+		prog.Data = append(prog.Data, fmt.Sprintf("%s DB 00", cel))
+	}
+
+	prog.Code = append(prog.Code,
+		"DIV_INICIO:",
+		fmt.Sprintf("LDA %s", zero),
+		"STA DIV_Q",
+		"LDA DIV_A",
+		"STA DIV_R",
+		"LDA DIV_B",
+		"JZ DIV_VOLTA",
+		"DIV_EXT:",
+		"LDA DIV_B",
+		"STA DIV_CONT",
+		"LDA DIV_R",
+		"STA DIV_SALVO",
+		"DIV_SUB:",
+		"LDA DIV_CONT",
+		"JZ DIV_OK",
+		"LDA DIV_R",
+		"JZ DIV_REST",
+		fmt.Sprintf("ADD %s", menosUm),
+		"STA DIV_R",
+		"LDA DIV_CONT",
+		fmt.Sprintf("ADD %s", menosUm),
+		"STA DIV_CONT",
+		"JMP DIV_SUB",
+		"DIV_OK:",
+		"LDA DIV_Q",
+		fmt.Sprintf("ADD %s", um),
+		"STA DIV_Q",
+		"JMP DIV_EXT",
+		"DIV_REST:",
+		"LDA DIV_SALVO",
+		"STA DIV_R",
+		"DIV_VOLTA:",
+		"JMP 00",
+	)
+}
+
+// texto reescreve a expressão como no código-fonte, para os comentários do
+// assembly gerado.
+func texto(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		return e.Value
+	case *ast.Ident:
+		return e.Name
+	case *ast.UnaryExpr:
+		return e.Op + operando(e.Operand)
+	case *ast.BinaryExpr:
+		return fmt.Sprintf("%s %s %s", operando(e.Left), e.Op, operando(e.Right))
+	}
+	return "?"
+}
+
+// operando é como texto, mas põe operações binárias entre parênteses.
+func operando(expr ast.Expr) string {
+	if _, ok := expr.(*ast.BinaryExpr); ok {
+		return "(" + texto(expr) + ")"
+	}
+	return texto(expr)
 }
\ No newline at end of file
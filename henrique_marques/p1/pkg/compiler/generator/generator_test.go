@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"p1/pkg/assembler"
+	"p1/pkg/encoder"
+)
+
+// executa gera o programa, monta e executa com a entrada dada (números
+// decimais) e devolve os valores escritos, um por linha.
+func executa(t *testing.T, corpo, entrada string) string {
+	t.Helper()
+	prog, err := GenerateASM(analisa(t, corpo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AlocaTemporarios(&prog)
+	a, err := assembler.Assemble(prog.String(), false, false)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, prog.String())
+	}
+	var imagem, saida bytes.Buffer
+	if err := a.EscreveMEM(&imagem); err != nil {
+		t.Fatal(err)
+	}
+	cpu := encoder.NewCPU()
+	if err := cpu.Load(imagem.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	err = cpu.RunWith(encoder.RunOptions{
+		MaxSteps: 100000,
+		Device:   encoder.NewTextDevice(strings.NewReader(entrada), &saida),
+	})
+	if err != nil {
+		t.Fatalf("entrada %q: %v", entrada, err)
+	}
+	return saida.String()
+}
+
+// As rotinas de multiplicação, divisão e resto dão, para cada par de
+// operandos, o mesmo resultado em 8 bits que a aritmética sem sinal do Go.
+// Divisão por zero resulta em quociente 0 e resto igual ao dividendo.
+func TestMultiplicacaoEDivisao(t *testing.T) {
+	valores := []uint8{0, 1, 2, 3, 7, 10, 16, 100, 127, 128, 200, 255}
+	operacoes := map[string]func(a, b uint8) uint8{
+		"*": func(a, b uint8) uint8 { return a * b },
+		"/": func(a, b uint8) uint8 {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		},
+		"%": func(a, b uint8) uint8 {
+			if b == 0 {
+				return a
+			}
+			return a % b
+		},
+	}
+	for op, f := range operacoes {
+		t.Run(op, func(t *testing.T) {
+			corpo := "LEIA A\nLEIA B\nESCREVA A " + op + " B"
+			for _, a := range valores {
+				for _, b := range valores {
+					esperada := fmt.Sprintln(f(a, b))
+					if obtida := executa(t, corpo, fmt.Sprint(a, " ", b)); obtida != esperada {
+						t.Errorf("%d %s %d = %q, esperado %q", a, op, b, obtida, esperada)
+					}
+				}
+			}
+		})
+	}
+}
+
+// E, OU e NAO geram o mesmo código que &, | e ~.
+func TestOperadoresPorExtenso(t *testing.T) {
+	casos := []struct{ extenso, simbolos string }{
+		{"A E B", "A & B"},
+		{"A OU B", "A | B"},
+		{"NAO A", "~A"},
+		{"NAO A E B OU A", "~A & B | A"},
+	}
+	valores := []uint8{0, 5, 12, 200, 255}
+	for _, c := range casos {
+		for _, a := range valores {
+			for _, b := range valores {
+				entrada := fmt.Sprint(a, " ", b)
+				obtida := executa(t, "LEIA A\nLEIA B\nESCREVA "+c.extenso, entrada)
+				esperada := executa(t, "LEIA A\nLEIA B\nESCREVA "+c.simbolos, entrada)
+				if obtida != esperada {
+					t.Errorf("A=%d, B=%d: %s deu %q, %s deu %q", a, b, c.extenso, obtida, c.simbolos, esperada)
+				}
+			}
+		}
+	}
+	if obtida := executa(t, "LEIA A\nLEIA B\nESCREVA A E B OU 1", "12 10"); obtida != "9\n" {
+		t.Errorf("12 E 10 OU 1 = %q, esperado 9", obtida)
+	}
+}
+
+// As comparações são sem sinal, entre variáveis ou com constantes de
+// qualquer metade da faixa, e os laços param na condição certa.
+func TestComparacoes(t *testing.T) {
+	valores := []uint8{0, 1, 100, 127, 128, 129, 200, 255}
+	compara := map[string]func(a, b uint8) bool{
+		"<":  func(a, b uint8) bool { return a < b },
+		">":  func(a, b uint8) bool { return a > b },
+		"<=": func(a, b uint8) bool { return a <= b },
+		">=": func(a, b uint8) bool { return a >= b },
+		"==": func(a, b uint8) bool { return a == b },
+		"!=": func(a, b uint8) bool { return a != b },
+	}
+	for op, f := range compara {
+		t.Run(op, func(t *testing.T) {
+			for _, b := range valores {
+				variavel := "LEIA A\nLEIA B\nSE A " + op + " B ENTAO\n ESCREVA 1\nSENAO\n ESCREVA 0\nFIMSE"
+				constante := fmt.Sprintf("LEIA A\nSE A %s %d ENTAO\n ESCREVA 1\nSENAO\n ESCREVA 0\nFIMSE", op, b)
+				for _, a := range valores {
+					esperada := "0\n"
+					if f(a, b) {
+						esperada = "1\n"
+					}
+					if obtida := executa(t, variavel, fmt.Sprint(a, " ", b)); obtida != esperada {
+						t.Errorf("A=%d, B=%d: A %s B deu %q", a, b, op, obtida)
+					}
+					if obtida := executa(t, constante, fmt.Sprint(a)); obtida != esperada {
+						t.Errorf("A=%d: A %s %d deu %q", a, op, b, obtida)
+					}
+				}
+			}
+		})
+	}
+
+	// Conta de 250 até 5, passando pela volta de 255 para 0.
+	laco := "LEIA A\nN = 0\nENQUANTO A != 5 FACA\n A = A + 1\n N = N + 1\nFIMENQUANTO\nESCREVA N"
+	if obtida := executa(t, laco, "250"); obtida != "11\n" {
+		t.Errorf("laço: %q, esperado 11", obtida)
+	}
+}
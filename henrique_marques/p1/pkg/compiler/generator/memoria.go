@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TamanhoMemoria é o número de palavras de memória do Neander.
+const TamanhoMemoria = 256
+
+// semOperando lista as instruções geradas que ocupam uma única palavra.
+var semOperando = map[string]bool{"NOP": true, "NOT": true, "HLT": true}
+
+// Uso conta as palavras de memória ocupadas pelo programa gerado.
+type Uso struct {
+	Codigo      int
+	Variaveis   int
+	Constantes  int
+	Temporarios int
+	// Divisao conta as posições da rotina de divisão e os ponteiros de
+	// retorno de cada chamada.
+	Divisao int
+	// ES conta os endereços de entrada e saída (FE e FF) reservados por
+	// LEIA e ESCREVA. Como os dados ficam logo após o código, os dois
+	// ficam reservados mesmo que o programa só use um deles.
+	ES int
+}
+
+func (u Uso) Dados() int {
+	return u.Variaveis + u.Constantes + u.Temporarios + u.Divisao + u.ES
+}
+
+func (u Uso) Total() int {
+	return u.Codigo + u.Dados()
+}
+
+// String monta o relatório de uso da memória.
+func (u Uso) String() string {
+	var sb strings.Builder
+	fmt.Fprintln(&sb, "Uso de memória (palavras):")
+	fmt.Fprintf(&sb, "  código       %3d\n", u.Codigo)
+	fmt.Fprintf(&sb, "  variáveis    %3d\n", u.Variaveis)
+	fmt.Fprintf(&sb, "  constantes   %3d\n", u.Constantes)
+	fmt.Fprintf(&sb, "  temporários  %3d\n", u.Temporarios)
+	if u.Divisao > 0 {
+		fmt.Fprintf(&sb, "  divisão      %3d\n", u.Divisao)
+	}
+	if u.ES > 0 {
+		fmt.Fprintf(&sb, "  E/S          %3d\n", u.ES)
+	}
+	fmt.Fprintf(&sb, "  total        %3d de %d (%d livres)\n", u.Total(), TamanhoMemoria, max(TamanhoMemoria-u.Total(), 0))
+	return sb.String()
+}
+
+// Memoria conta quantas palavras o programa ocupa, separando o código das
+// posições de dados por tipo.
+func (prog ASMProgram) Memoria() Uso {
+	var u Uso
+	for _, linha := range prog.Code {
+		op, _, ok := instrucao(linha)
+		switch {
+		case !ok, op == "ORG", strings.HasPrefix(op, "."):
+		case semOperando[op]:
+			u.Codigo++
+		default:
+			u.Codigo += 2
+		}
+	}
+	for _, linha := range prog.Data {
+		campos := strings.Fields(linha)
+		if len(campos) > 0 && isES(campos[0]) {
+			u.ES = 2
+			continue
+		}
+		if len(campos) < 2 || campos[1] != "DB" {
+			continue
+		}
+		switch tipoDado(campos[0]) {
+		case "temporário":
+			u.Temporarios++
+		case "constante":
+			u.Constantes++
+		case "divisão":
+			u.Divisao++
+		default:
+			u.Variaveis++
+		}
+	}
+	return u
+}
+
+// tipoDado classifica uma posição de dados pelo nome que o gerador lhe deu.
+func tipoDado(nome string) string {
+	switch {
+	case isES(nome):
+		return "E/S"
+	case tmpRegex.MatchString(nome):
+		return "temporário"
+	case strings.HasPrefix(nome, "CONST_"):
+		return "constante"
+	case strings.HasPrefix(nome, "DIV_"), strings.HasPrefix(nome, "PTR_"):
+		return "divisão"
+	}
+	return "variável"
+}
+
+// Celula é uma posição da seção de dados e o endereço que ela recebe.
+type Celula struct {
+	Endereco  int
+	Nome      string
+	Tipo      string
+	Valor     string
+	Descricao string
+}
+
+// Layout é a disposição dos dados do programa, em ordem de endereço.
+type Layout []Celula
+
+// Layout calcula o endereço de cada posição de dados. O assembler põe a
+// seção de dados logo após o código, uma palavra por DB na ordem em que
+// aparecem, então os endereços saem do tamanho do código. Os rótulos de
+// E/S (EQU) ficam com os endereços fixos, no fim.
+func (prog ASMProgram) Layout() Layout {
+	var layout Layout
+	endereco := prog.Memoria().Codigo
+	for _, linha := range prog.Data {
+		linha, descricao, _ := strings.Cut(linha, ";")
+		campos := strings.Fields(linha)
+		if len(campos) < 3 {
+			continue
+		}
+		c := Celula{Nome: campos[0], Tipo: tipoDado(campos[0]), Valor: campos[2], Descricao: strings.TrimSpace(descricao)}
+		switch campos[1] {
+		case "DB":
+			c.Endereco = endereco
+			endereco++
+		case "EQU":
+			n, err := strconv.ParseUint(campos[2], 16, 8)
+			if err != nil {
+				continue
+			}
+			c.Endereco, c.Valor = int(n), ""
+		default:
+			continue
+		}
+		layout = append(layout, c)
+	}
+	sort.SliceStable(layout, func(i, j int) bool { return layout[i].Endereco < layout[j].Endereco })
+	return layout
+}
+
+// String monta o relatório da disposição dos dados.
+func (l Layout) String() string {
+	largura := 0
+	for _, c := range l {
+		largura = max(largura, len(c.Nome))
+	}
+	var sb strings.Builder
+	fmt.Fprintln(&sb, "Disposição dos dados:")
+	for _, c := range l {
+		linha := fmt.Sprintf("  %02X  %-*s  %-10s  %s", c.Endereco, largura, c.Nome, c.Tipo, c.Valor)
+		if c.Descricao != "" {
+			linha += "  ; " + c.Descricao
+		}
+		fmt.Fprintln(&sb, strings.TrimRight(linha, " "))
+	}
+	return sb.String()
+}
+
+// String junta o código e os dados no texto do assembly.
+func (prog ASMProgram) String() string {
+	return strings.Join(append(append([]string{}, prog.Code...), prog.Data...), "\n")
+}
@@ -0,0 +1,266 @@
+package generator
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"p1/pkg/compiler/ast"
+	"p1/pkg/diag"
+)
+
+var tmpRegex = regexp.MustCompile(`^TMP[0-9]+$`)
+
+// DobraConstantes substitui, nas expressões do programa, as operações entre
+// literais pelo seu resultado, calculado em 8 bits como no Neander. As
+// comparações das condições não são dobradas, apenas seus operandos.
+func DobraConstantes(programa *ast.Program) {
+	dobraInstrucoes(programa.Body)
+}
+
+func dobraInstrucoes(instrucoes []ast.Stmt) {
+	for _, inst := range instrucoes {
+		switch inst := inst.(type) {
+		case *ast.Assign:
+			inst.Value = dobra(inst.Value)
+		case *ast.Write:
+			inst.Value = dobra(inst.Value)
+		case *ast.If:
+			dobraCondicao(inst.Cond)
+			dobraInstrucoes(inst.Then)
+			dobraInstrucoes(inst.Else)
+		case *ast.While:
+			dobraCondicao(inst.Cond)
+			dobraInstrucoes(inst.Body)
+		}
+	}
+}
+
+func dobraCondicao(cond ast.Expr) {
+	if c, ok := cond.(*ast.BinaryExpr); ok && ast.IsRelational(c.Op) {
+		c.Left = dobra(c.Left)
+		c.Right = dobra(c.Right)
+	}
+}
+
+// dobra devolve a expressão com as subexpressões constantes já calculadas.
+func dobra(expr ast.Expr) ast.Expr {
+	if u, ok := expr.(*ast.UnaryExpr); ok {
+		u.Operand = dobra(u.Operand)
+		a, ok := valorLiteral(u.Operand)
+		if !ok {
+			return u
+		}
+		switch u.Op {
+		case "-":
+			return literal(u.Pos, -a)
+		case "~":
+			return literal(u.Pos, ^a)
+		}
+		return u
+	}
+	e, ok := expr.(*ast.BinaryExpr)
+	if !ok {
+		return expr
+	}
+	e.Left = dobra(e.Left)
+	e.Right = dobra(e.Right)
+	a, okA := valorLiteral(e.Left)
+	b, okB := valorLiteral(e.Right)
+	if !okA || !okB {
+		return e
+	}
+
+	var r uint8
+	switch e.Op {
+	case "+":
+		r = a + b
+	case "-":
+		r = a - b
+	case "*":
+		r = a * b
+	case "/", "%":
+		// Como na rotina de divisão: dividir por zero dá quociente 0 e
+		// resto igual ao dividendo.
+		q, resto := uint8(0), a
+		if b != 0 {
+			q, resto = a/b, a%b
+		}
+		r = q
+		if e.Op == "%" {
+			r = resto
+		}
+	case "&":
+		r = a & b
+	case "|":
+		r = a | b
+	case "<<":
+		r = a << b
+	case ">>":
+		r = a >> b
+	default:
+		return e
+	}
+	return literal(e.Pos, r)
+}
+
+// literal cria o literal resultante de uma dobra, escrito em decimal.
+func literal(pos diag.Pos, valor uint8) *ast.Literal {
+	return &ast.Literal{Pos: pos, Value: strconv.Itoa(int(valor)), Number: valor}
+}
+
+func valorLiteral(expr ast.Expr) (uint8, bool) {
+	l, ok := expr.(*ast.Literal)
+	if !ok {
+		return 0, false
+	}
+	return l.Number, true
+}
+
+// Otimiza aplica ao assembly gerado otimizações de janela (peephole) até
+// que nenhuma altere mais o código:
+//
+//   - STA X seguido de LDA X: o LDA é removido, o AC já tem o valor;
+//   - LDA X seguido de STA X: o STA é removido, a memória já tem o valor;
+//   - LDA X seguido de LDA Y: o primeiro LDA é removido;
+//   - JMP para o rótulo da linha seguinte é removido;
+//   - STA em um temporário que nunca é lido é removido.
+//
+// Acessos aos endereços de E/S nunca são removidos: cada um lê ou escreve
+// um valor no dispositivo.
+//
+// Com isso, o resultado de uma expressão é gravado direto na variável, sem
+// passar por um temporário. Ao final, temporários e constantes que não são
+// mais usados saem da seção de dados. Rótulos separam as janelas, já que
+// o código pode chegar a eles por desvios; as marcas de linha não.
+func Otimiza(prog *ASMProgram) {
+	for mudou := true; mudou; {
+		mudou = false
+		for _, regra := range []func([]string) ([]string, bool){acessosRedundantes, saltosParaSeguinte, escritasMortas} {
+			var m bool
+			prog.Code, m = regra(prog.Code)
+			mudou = mudou || m
+		}
+	}
+	prog.Data = dadosUsados(prog)
+}
+
+// instrucao separa a linha em mnemônico e operando. Rótulos e comentários
+// não são instruções.
+func instrucao(linha string) (op, arg string, ok bool) {
+	campos := strings.Fields(linha)
+	if len(campos) == 0 || strings.HasPrefix(campos[0], ";") || strings.HasSuffix(campos[0], ":") {
+		return "", "", false
+	}
+	if len(campos) > 1 {
+		arg = campos[1]
+	}
+	return campos[0], arg, true
+}
+
+func isES(arg string) bool {
+	return arg == EntradaES || arg == SaidaES
+}
+
+func isComentario(linha string) bool {
+	return strings.HasPrefix(strings.TrimSpace(linha), ";")
+}
+
+// seguinte devolve o índice da linha depois de i, pulando comentários, ou
+// -1 se não houver.
+func seguinte(code []string, i int) int {
+	for j := i + 1; j < len(code); j++ {
+		if !isComentario(code[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+func remove(code []string, i int) []string {
+	return append(code[:i], code[i+1:]...)
+}
+
+func acessosRedundantes(code []string) ([]string, bool) {
+	mudou := false
+	for i := 0; i < len(code); i++ {
+		op, arg, ok := instrucao(code[i])
+		if !ok || (op != "LDA" && op != "STA") {
+			continue
+		}
+		j := seguinte(code, i)
+		if j < 0 {
+			break
+		}
+		opJ, argJ, ok := instrucao(code[j])
+		switch {
+		case !ok, isES(arg), isES(argJ):
+		case op != opJ && arg == argJ && (opJ == "LDA" || opJ == "STA"):
+			code = remove(code, j)
+			mudou = true
+			i--
+		case op == "LDA" && opJ == "LDA":
+			code = remove(code, i)
+			mudou = true
+			i--
+		}
+	}
+	return code, mudou
+}
+
+func saltosParaSeguinte(code []string) ([]string, bool) {
+	mudou := false
+	for i := 0; i < len(code); i++ {
+		op, destino, ok := instrucao(code[i])
+		if !ok || op != "JMP" {
+			continue
+		}
+		for j := seguinte(code, i); j >= 0 && strings.HasSuffix(code[j], ":"); j = seguinte(code, j) {
+			if code[j] == destino+":" {
+				code = remove(code, i)
+				mudou = true
+				i--
+				break
+			}
+		}
+	}
+	return code, mudou
+}
+
+func escritasMortas(code []string) ([]string, bool) {
+	lidos := map[string]bool{}
+	for _, linha := range code {
+		if op, arg, ok := instrucao(linha); ok && op != "STA" {
+			lidos[arg] = true
+		}
+	}
+	mudou := false
+	for i := 0; i < len(code); i++ {
+		if op, arg, ok := instrucao(code[i]); ok && op == "STA" && tmpRegex.MatchString(arg) && !lidos[arg] {
+			code = remove(code, i)
+			mudou = true
+			i--
+		}
+	}
+	return code, mudou
+}
+
+// dadosUsados devolve a seção de dados sem os temporários e constantes que
+// o código não usa mais.
+func dadosUsados(prog *ASMProgram) []string {
+	usados := map[string]bool{}
+	for _, linha := range prog.Code {
+		if _, arg, ok := instrucao(linha); ok {
+			usados[arg] = true
+		}
+	}
+	dados := []string{}
+	for _, linha := range prog.Data {
+		campos := strings.Fields(linha)
+		if len(campos) > 0 && (tmpRegex.MatchString(campos[0]) || strings.HasPrefix(campos[0], "CONST_")) && !usados[campos[0]] {
+			continue
+		}
+		dados = append(dados, linha)
+	}
+	return dados
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"p1/pkg/compiler/ast"
+	"p1/pkg/compiler/lexer"
+	"p1/pkg/compiler/parser"
+)
+
+func analisa(t *testing.T, corpo string) *ast.Program {
+	t.Helper()
+	tokens, err := lexer.Lex("PROGRAMA \"T\"\nINICIO\n" + corpo + "\nFIM\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	programa, err := parser.NewParser(tokens).ParsePrograma()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return programa
+}
+
+// expressoes devolve o texto das expressões de cada instrução, na ordem.
+func expressoes(instrucoes []ast.Stmt) []string {
+	var textos []string
+	for _, inst := range instrucoes {
+		switch inst := inst.(type) {
+		case *ast.Assign:
+			textos = append(textos, texto(inst.Value))
+		case *ast.Write:
+			textos = append(textos, texto(inst.Value))
+		case *ast.If:
+			textos = append(textos, texto(inst.Cond))
+			textos = append(textos, expressoes(inst.Then)...)
+			textos = append(textos, expressoes(inst.Else)...)
+		case *ast.While:
+			textos = append(textos, texto(inst.Cond))
+			textos = append(textos, expressoes(inst.Body)...)
+		}
+	}
+	return textos
+}
+
+func TestDobraConstantes(t *testing.T) {
+	casos := []struct {
+		corpo     string
+		esperadas []string
+	}{
+		{corpo: "A = 3 + 4 - 2", esperadas: []string{"5"}},
+		{corpo: "A = B + 2 * 3", esperadas: []string{"B + 6"}},
+		{corpo: "A = 200 + 100", esperadas: []string{"44"}},
+		{corpo: "A = -1", esperadas: []string{"255"}},
+		{corpo: "A = ~0x0F & 0xFF", esperadas: []string{"240"}},
+		{corpo: "A = 1 << 3 | 7 % 4", esperadas: []string{"11"}},
+		{corpo: "A = 5 / 0 + 5 % 0", esperadas: []string{"5"}},
+		{corpo: "ESCREVA 2 * 3", esperadas: []string{"6"}},
+		{corpo: "SE A < 2 * 3 ENTAO\n ESCREVA 1 + 1\nSENAO\n B = 4 - 1\nFIMSE", esperadas: []string{"A < 6", "2", "3"}},
+		{corpo: "ENQUANTO 10 / 2 > A FACA\n A = A + 1 * 1\nFIMENQUANTO", esperadas: []string{"5 > A", "A + 1"}},
+	}
+	for _, c := range casos {
+		programa := analisa(t, c.corpo)
+		DobraConstantes(programa)
+		if obtidas := expressoes(programa.Body); strings.Join(obtidas, "; ") != strings.Join(c.esperadas, "; ") {
+			t.Errorf("%q: dobrado como %q, esperado %q", c.corpo, obtidas, c.esperadas)
+		}
+	}
+}
+
+// Com a expressão dobrada e o assembly otimizado, uma atribuição constante
+// vira um LDA da constante e um STA direto na variável.
+func TestOtimiza(t *testing.T) {
+	programa := analisa(t, "A = 2 * 3")
+	DobraConstantes(programa)
+	prog, err := GenerateASM(programa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Otimiza(&prog)
+	asm := prog.String()
+	if strings.Contains(asm, "MUL") || strings.Contains(asm, "TMP") || !strings.Contains(asm, "LDA CONST_06\nSTA A") {
+		t.Errorf("assembly de A = 2 * 3:\n%s", asm)
+	}
+}
+
+// Com a expressão dobrada, ESCREVA de uma constante não gera o laço da
+// multiplicação.
+func TestOtimizaEscreva(t *testing.T) {
+	programa := analisa(t, "ESCREVA 2 * 3")
+	DobraConstantes(programa)
+	prog, err := GenerateASM(programa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Otimiza(&prog)
+	asm := prog.String()
+	if strings.Contains(asm, "MUL") || !strings.Contains(asm, "LDA CONST_06\nSTA "+SaidaES) {
+		t.Errorf("assembly de ESCREVA 2 * 3:\n%s", asm)
+	}
+}
@@ -0,0 +1,31 @@
+package generator
+
+import (
+	asmlexer "p1/pkg/assembler/lexer"
+	"p1/pkg/compiler/lexer"
+)
+
+// NomeReservado informa se nome não pode ser usado como variável, e por
+// quê. E, OU e NAO são operadores da linguagem, os temporários (TMPn)
+// colidiriam com os que o gerador cria, e as diretivas (DB, ORG, ...) e
+// instruções (NOT, ADD, ...) do assembler não são aceitas como rótulos:
+// STA NOT seria montado com o opcode de NOT como operando. Os demais
+// rótulos gerados (CONST_, DIV_, PTR_, ES_ e os de desvio) têm '_', que
+// não aparece em nomes de variáveis.
+func NomeReservado(nome string) (motivo string, reservado bool) {
+	_, neander := asmlexer.Instructions[nome]
+	_, ahmes := asmlexer.AhmesInstructions[nome]
+	switch {
+	case lexer.OperadoresPorExtenso[nome] != "":
+		return "um operador da linguagem", true
+	case tmpRegex.MatchString(nome):
+		return "reservado para os temporários do compilador", true
+	case asmlexer.Define[nome]:
+		return "uma diretiva do assembler", true
+	case neander:
+		return "uma instrução do Neander", true
+	case ahmes:
+		return "uma instrução do Ahmes", true
+	}
+	return "", false
+}
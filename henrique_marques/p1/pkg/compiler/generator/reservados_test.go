@@ -0,0 +1,26 @@
+package generator
+
+import "testing"
+
+func TestNomeReservado(t *testing.T) {
+	casos := []struct {
+		nome   string
+		motivo string
+	}{
+		{"E", "um operador da linguagem"},
+		{"OU", "um operador da linguagem"},
+		{"NAO", "um operador da linguagem"},
+		{"TMP1", "reservado para os temporários do compilador"},
+		{"DB", "uma diretiva do assembler"},
+		{"NOT", "uma instrução do Neander"},
+		{"SHL", "uma instrução do Ahmes"},
+		{"A", ""},
+		{"ENTRADA", ""},
+	}
+	for _, c := range casos {
+		motivo, reservado := NomeReservado(c.nome)
+		if motivo != c.motivo || reservado != (c.motivo != "") {
+			t.Errorf("NomeReservado(%q) = %q, %v; esperado %q", c.nome, motivo, reservado, c.motivo)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// intervalo é o trecho do código, em índices de prog.Code, em que um
+// temporário está vivo.
+type intervalo struct {
+	nome        string
+	inicio, fim int
+}
+
+// AlocaTemporarios faz os temporários que nunca estão vivos ao mesmo tempo
+// dividirem a mesma posição de memória. O gerador cria um temporário novo
+// para cada operação; aqui eles são renomeados para TMP0, TMP1, ... de modo
+// que o número de posições seja o máximo de temporários vivos em um mesmo
+// ponto do programa.
+//
+// Um temporário está vivo da primeira à última linha que o usa. Se ele já
+// estava vivo ao entrar em um laço (um desvio para trás), continua vivo até
+// o desvio, porque a próxima volta do laço ainda pode lê-lo. Um temporário
+// lido antes de ser escrito nunca é compartilhado.
+func AlocaTemporarios(prog *ASMProgram) {
+	intervalos := vidaDosTemporarios(prog.Code)
+
+	// Alocação por varredura linear: cada temporário, na ordem em que
+	// nasce, fica com a primeira posição livre.
+	sort.Slice(intervalos, func(i, j int) bool {
+		if intervalos[i].inicio != intervalos[j].inicio {
+			return intervalos[i].inicio < intervalos[j].inicio
+		}
+		return intervalos[i].nome < intervalos[j].nome
+	})
+	var ocupadaAte []int
+	novo := map[string]string{}
+	for _, iv := range intervalos {
+		celula := len(ocupadaAte)
+		for c, fim := range ocupadaAte {
+			if fim < iv.inicio {
+				celula = c
+				break
+			}
+		}
+		if celula == len(ocupadaAte) {
+			ocupadaAte = append(ocupadaAte, iv.fim)
+		} else {
+			ocupadaAte[celula] = iv.fim
+		}
+		novo[iv.nome] = fmt.Sprintf("TMP%d", celula)
+	}
+
+	for i, linha := range prog.Code {
+		if op, arg, ok := instrucao(linha); ok && novo[arg] != "" {
+			prog.Code[i] = op + " " + novo[arg]
+		}
+	}
+
+	// As declarações antigas dão lugar a uma por posição, com as
+	// subexpressões de todos os temporários que a usam.
+	descricoes := make([][]string, len(ocupadaAte))
+	dados := []string{}
+	for _, linha := range prog.Data {
+		campos := strings.Fields(linha)
+		if len(campos) == 0 || !tmpRegex.MatchString(campos[0]) {
+			dados = append(dados, linha)
+			continue
+		}
+		nome, ok := novo[campos[0]]
+		if !ok {
+			// Não é usado pelo código.
+			continue
+		}
+		var celula int
+		fmt.Sscanf(nome, "TMP%d", &celula)
+		if _, descricao, ok := strings.Cut(linha, ";"); ok {
+			descricoes[celula] = append(descricoes[celula], strings.TrimSpace(descricao))
+		}
+	}
+	for celula, d := range descricoes {
+		declaracao := fmt.Sprintf("TMP%d DB 00", celula)
+		if len(d) > 0 {
+			declaracao += " ; " + strings.Join(d, ", ")
+		}
+		dados = append(dados, declaracao)
+	}
+	prog.Data = dados
+}
+
+func vidaDosTemporarios(code []string) []*intervalo {
+	porNome := map[string]*intervalo{}
+	var intervalos []*intervalo
+	rotulos := map[string]int{}
+	for i, linha := range code {
+		if nome, ok := strings.CutSuffix(strings.TrimSpace(linha), ":"); ok {
+			rotulos[nome] = i
+			continue
+		}
+		op, arg, ok := instrucao(linha)
+		if !ok || !tmpRegex.MatchString(arg) {
+			continue
+		}
+		iv := porNome[arg]
+		if iv == nil {
+			iv = &intervalo{nome: arg, inicio: i, fim: i}
+			if op != "STA" {
+				iv.inicio, iv.fim = 0, len(code)
+			}
+			porNome[arg] = iv
+			intervalos = append(intervalos, iv)
+		}
+		iv.fim = max(iv.fim, i)
+	}
+
+	type laco struct{ inicio, fim int }
+	var lacos []laco
+	for i, linha := range code {
+		op, arg, ok := instrucao(linha)
+		if !ok || (op != "JMP" && op != "JN" && op != "JZ") {
+			continue
+		}
+		if destino, ok := rotulos[arg]; ok && destino < i {
+			lacos = append(lacos, laco{destino, i})
+		}
+	}
+	for mudou := true; mudou; {
+		mudou = false
+		for _, l := range lacos {
+			for _, iv := range intervalos {
+				if iv.inicio < l.inicio && iv.fim >= l.inicio && iv.fim < l.fim {
+					iv.fim = l.fim
+					mudou = true
+				}
+			}
+		}
+	}
+	return intervalos
+}
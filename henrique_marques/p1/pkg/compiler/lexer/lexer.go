@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"errors"
 	"fmt"
+	"p1/pkg/diag"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -11,6 +14,7 @@ type TokenType string
 type Token struct {
 	Tipo  TokenType
 	Valor string
+	Pos   diag.Pos
 }
 
 const (
@@ -24,76 +28,196 @@ const (
 	TOKEN_ATRIB     TokenType = "="
 	TOKEN_ABREPAR   TokenType = "("
 	TOKEN_FECHAPAR  TokenType = ")"
+	TOKEN_VIRGULA   TokenType = ","
 	TOKEN_NEWLINE   TokenType = "\n"
 	TOKEN_EOF       TokenType = "EOF"
+	TOKEN_RELOP     TokenType = "RELOP"
+
+	TOKEN_SE          TokenType = "SE"
+	TOKEN_ENTAO       TokenType = "ENTAO"
+	TOKEN_SENAO       TokenType = "SENAO"
+	TOKEN_FIMSE       TokenType = "FIMSE"
+	TOKEN_ENQUANTO    TokenType = "ENQUANTO"
+	TOKEN_FACA        TokenType = "FACA"
+	TOKEN_FIMENQUANTO TokenType = "FIMENQUANTO"
+	TOKEN_LEIA        TokenType = "LEIA"
+	TOKEN_ESCREVA     TokenType = "ESCREVA"
+	TOKEN_VARIAVEIS   TokenType = "VARIAVEIS"
 )
 
-var operadores = "+-*/"
+var palavrasChave = map[string]TokenType{
+	"PROGRAMA":    TOKEN_PROGRAMA,
+	"INICIO":      TOKEN_INICIO,
+	"FIM":         TOKEN_FIM,
+	"SE":          TOKEN_SE,
+	"ENTAO":       TOKEN_ENTAO,
+	"SENAO":       TOKEN_SENAO,
+	"FIMSE":       TOKEN_FIMSE,
+	"ENQUANTO":    TOKEN_ENQUANTO,
+	"FACA":        TOKEN_FACA,
+	"FIMENQUANTO": TOKEN_FIMENQUANTO,
+	"LEIA":        TOKEN_LEIA,
+	"ESCREVA":     TOKEN_ESCREVA,
+	"VARIAVEIS":   TOKEN_VARIAVEIS,
+}
 
-func isLetter(r rune) bool {
-	return unicode.IsLetter(r)
+// OperadoresPorExtenso são as palavras-chave que escrevem por extenso os
+// operadores bit a bit. Viram o mesmo token do símbolo e, portanto, têm a
+// mesma precedência: A E B OU NAO C é A & B | ~C.
+var OperadoresPorExtenso = map[string]string{
+	"E":   "&",
+	"OU":  "|",
+	"NAO": "~",
 }
 
-func isHexDigit(r rune) bool {
-	return unicode.IsDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+var operadores = "+-*/%&|~"
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r)
 }
 
 func isVarChar(r rune) bool {
 	return unicode.IsLetter(r) || unicode.IsDigit(r)
 }
 
+// Numero converte um literal numérico no seu valor de 8 bits. São aceitos
+// decimais (10 ou 0d10), hexadecimais (0x0A ou 0Ah), binários (0b1010) e
+// caracteres ('A'). Valores acima de 255 são erro.
+func Numero(texto string) (uint8, error) {
+	if runes := []rune(texto); len(runes) > 0 && runes[0] == '\'' {
+		if len(runes) != 3 || runes[2] != '\'' {
+			return 0, fmt.Errorf("caractere inválido: %s", texto)
+		}
+		if runes[1] > 0xFF {
+			return 0, fmt.Errorf("caractere fora do intervalo de 8 bits (0 a 255): %s", texto)
+		}
+		return uint8(runes[1]), nil
+	}
+
+	base, digitos := 10, texto
+	minusculo := strings.ToLower(texto)
+	switch {
+	// O sufixo vem primeiro: em "0B1h" o "0b" faz parte do número.
+	case strings.HasSuffix(minusculo, "h"):
+		base, digitos = 16, texto[:len(texto)-1]
+	case strings.HasPrefix(minusculo, "0x"):
+		base, digitos = 16, texto[2:]
+	case strings.HasPrefix(minusculo, "0b"):
+		base, digitos = 2, texto[2:]
+	case strings.HasPrefix(minusculo, "0d"):
+		digitos = texto[2:]
+	}
+	valor, err := strconv.ParseUint(digitos, base, 64)
+	if errors.Is(err, strconv.ErrRange) || (err == nil && valor > 0xFF) {
+		return 0, fmt.Errorf("número fora do intervalo de 8 bits (0 a 255): %s", texto)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("número inválido: %s", texto)
+	}
+	return uint8(valor), nil
+}
+
+// Lex separa o código em tokens. Caracteres inválidos e strings não
+// terminadas são registrados com sua posição e ignorados, para que todos os
+// erros léxicos sejam reportados de uma vez; nesse caso o erro retornado é
+// uma diag.Lista.
 func Lex(code string) ([]Token, error) {
 	tokens := []Token{}
+	var erros diag.Lista
 	i := 0
 	runes := []rune(code)
+	linha, inicioLinha := 1, 0
+
+	pos := func(indice int) diag.Pos {
+		return diag.Pos{Linha: linha, Coluna: indice - inicioLinha + 1}
+	}
+	add := func(tipo TokenType, valor string, inicio int) {
+		tokens = append(tokens, Token{Tipo: tipo, Valor: valor, Pos: pos(inicio)})
+	}
+
 	for i < len(runes) {
 		c := runes[i]
-		
+
 		if unicode.IsSpace(c) && c != '\n' {
 			i++
 			continue
 		}
 
+		// Comentários vão do ';' até o fim da linha.
+		if c == ';' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
 		if c == '\n' {
-			tokens = append(tokens, Token{TOKEN_NEWLINE, "\\n"})
+			add(TOKEN_NEWLINE, "\\n", i)
 			i++
+			linha, inicioLinha = linha+1, i
 			continue
 		}
 
-		if c == '=' {
-			tokens = append(tokens, Token{TOKEN_ATRIB, "="})
+		// "<<" e ">>" são deslocamentos; "<", ">", "<=" e ">=", comparações.
+		if (c == '<' || c == '>') && i+1 < len(runes) && runes[i+1] == c {
+			add(TOKEN_OP, string(c)+string(c), i)
+			i += 2
+			continue
+		}
+
+		if c == '=' || c == '<' || c == '>' || c == '!' {
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				add(TOKEN_RELOP, string(c)+"=", i)
+				i += 2
+				continue
+			}
+			switch c {
+			case '=':
+				add(TOKEN_ATRIB, "=", i)
+			case '!':
+				erros.Add(pos(i), "esperado '=' após '!'")
+			default:
+				add(TOKEN_RELOP, string(c), i)
+			}
 			i++
 			continue
 		}
 
 		if strings.ContainsRune(operadores, c) {
-			tokens = append(tokens, Token{TOKEN_OP, string(c)})
+			add(TOKEN_OP, string(c), i)
 			i++
 			continue
 		}
 
 		if c == '(' {
-			tokens = append(tokens, Token{TOKEN_ABREPAR, "("})
+			add(TOKEN_ABREPAR, "(", i)
 			i++
 			continue
 		}
 
 		if c == ')' {
-			tokens = append(tokens, Token{TOKEN_FECHAPAR, ")"})
+			add(TOKEN_FECHAPAR, ")", i)
+			i++
+			continue
+		}
+
+		if c == ',' {
+			add(TOKEN_VIRGULA, ",", i)
 			i++
 			continue
 		}
 
 		if c == '"' {
 			j := i + 1
-			for j < len(runes) && runes[j] != '"' {
+			for j < len(runes) && runes[j] != '"' && runes[j] != '\n' {
 				j++
 			}
-			if j >= len(runes) {
-				return nil, fmt.Errorf("string não terminada")
+			if j >= len(runes) || runes[j] != '"' {
+				erros.Add(pos(i), "string não terminada")
+				i = j
+				continue
 			}
-			valor := string(runes[i+1 : j])
-			tokens = append(tokens, Token{TOKEN_LABEL, valor})
+			add(TOKEN_LABEL, string(runes[i+1:j]), i)
 			i = j + 1
 			continue
 		}
@@ -104,34 +228,58 @@ func Lex(code string) ([]Token, error) {
 				j++
 			}
 			palavra := string(runes[i:j])
-			switch palavra {
-			case "PROGRAMA":
-				tokens = append(tokens, Token{TOKEN_PROGRAMA, palavra})
-			case "INICIO":
-				tokens = append(tokens, Token{TOKEN_INICIO, palavra})
-			case "FIM":
-				tokens = append(tokens, Token{TOKEN_FIM, palavra})
-			default:
-				tokens = append(tokens, Token{TOKEN_VAR, palavra})
+			if tipo, ok := palavrasChave[palavra]; ok {
+				add(tipo, palavra, i)
+			} else if op, ok := OperadoresPorExtenso[palavra]; ok {
+				add(TOKEN_OP, op, i)
+			} else {
+				add(TOKEN_VAR, palavra, i)
 			}
 			i = j
 			continue
 		}
 
-		if isHexDigit(c) {
+		// Um número começa por um dígito e vai até o fim da palavra, que
+		// inclui o prefixo ou sufixo da base. Números inválidos são
+		// reportados, mas viram tokens para que a análise continue.
+		if unicode.IsDigit(c) {
 			j := i
-			for j < len(runes) && isHexDigit(runes[j]) {
+			for j < len(runes) && isVarChar(runes[j]) {
 				j++
 			}
-			num := string(runes[i:j])
-			tokens = append(tokens, Token{TOKEN_NUM, num})
+			texto := string(runes[i:j])
+			if _, err := Numero(texto); err != nil {
+				erros.Add(pos(i), "%v", err)
+			}
+			add(TOKEN_NUM, texto, i)
+			i = j
+			continue
+		}
+
+		if c == '\'' {
+			j := i + 1
+			for j < len(runes) && runes[j] != '\'' && runes[j] != '\n' {
+				j++
+			}
+			if j < len(runes) && runes[j] == '\'' {
+				j++
+			}
+			texto := string(runes[i:j])
+			if _, err := Numero(texto); err != nil {
+				erros.Add(pos(i), "%v", err)
+			}
+			add(TOKEN_NUM, texto, i)
 			i = j
 			continue
 		}
 
-		return nil, fmt.Errorf("caractere inesperado: %c", c)
+		erros.Add(pos(i), "caractere inesperado: %c", c)
+		i++
 	}
 
-	tokens = append(tokens, Token{TOKEN_EOF, ""})
+	add(TOKEN_EOF, "", i)
+	if len(erros) > 0 {
+		return tokens, erros
+	}
 	return tokens, nil
 }
@@ -0,0 +1,54 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumero(t *testing.T) {
+	casos := []struct {
+		texto string
+		valor uint8
+		erro  string
+	}{
+		{texto: "10", valor: 10},
+		{texto: "0d10", valor: 10},
+		{texto: "0x0A", valor: 10},
+		{texto: "0Ah", valor: 10},
+		{texto: "0b1010", valor: 10},
+		{texto: "'A'", valor: 'A'},
+		{texto: "219", valor: 0xDB},
+		{texto: "0xDB", valor: 0xDB},
+		{texto: "255", valor: 255},
+		{texto: "256", erro: "fora do intervalo"},
+		{texto: "0x100", erro: "fora do intervalo"},
+		{texto: "0b2", erro: "número inválido"},
+		{texto: "12AB", erro: "número inválido"},
+		{texto: "'AB'", erro: "caractere inválido"},
+	}
+	for _, c := range casos {
+		valor, err := Numero(c.texto)
+		switch {
+		case c.erro == "" && (err != nil || valor != c.valor):
+			t.Errorf("Numero(%q) = %d, %v; esperado %d", c.texto, valor, err, c.valor)
+		case c.erro != "" && (err == nil || !strings.Contains(err.Error(), c.erro)):
+			t.Errorf("Numero(%q): erro %v, esperado %q", c.texto, err, c.erro)
+		}
+	}
+}
+
+// Um literal inválido é reportado na sua posição, mas continua sendo um
+// número para o parser.
+func TestLiteralInvalido(t *testing.T) {
+	tokens, err := Lex("A = 256 + 1\n")
+	if err == nil || !strings.Contains(err.Error(), "1:5: número fora do intervalo") {
+		t.Fatalf("erro %v, esperado número fora do intervalo em 1:5", err)
+	}
+	var tipos []string
+	for _, token := range tokens {
+		tipos = append(tipos, string(token.Tipo))
+	}
+	if got := strings.Join(tipos, " "); got != "VAR = NUM OP NUM \n EOF" {
+		t.Errorf("tokens %q", got)
+	}
+}
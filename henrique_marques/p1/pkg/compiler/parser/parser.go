@@ -1,18 +1,15 @@
 package parser
 
 import (
-	"fmt"
+	"p1/pkg/compiler/ast"
 	"p1/pkg/compiler/lexer"
+	"p1/pkg/diag"
 )
 
-type Instrucao struct {
-	Var  string
-	Expr []lexer.Token
-}
-
 type Parser struct {
 	tokens []lexer.Token
 	pos    int
+	erros  diag.Lista
 }
 
 func NewParser(tokens []lexer.Token) *Parser {
@@ -21,6 +18,9 @@ func NewParser(tokens []lexer.Token) *Parser {
 
 func (p *Parser) current() lexer.Token {
 	if p.pos >= len(p.tokens) {
+		if len(p.tokens) > 0 {
+			return lexer.Token{Tipo: lexer.TOKEN_EOF, Pos: p.tokens[len(p.tokens)-1].Pos}
+		}
 		return lexer.Token{Tipo: lexer.TOKEN_EOF}
 	}
 	return p.tokens[p.pos]
@@ -40,112 +40,341 @@ func (p *Parser) match(t lexer.TokenType) bool {
 	return false
 }
 
+// erro cria um erro na posição do token atual.
+func (p *Parser) erro(format string, args ...any) error {
+	return diag.Errorf(p.current().Pos, format, args...)
+}
+
+// registra guarda o erro e descarta o resto da linha, para que a análise
+// continue na linha seguinte.
+func (p *Parser) registra(err error) {
+	p.erros = append(p.erros, err.(*diag.Erro))
+	for p.current().Tipo != lexer.TOKEN_NEWLINE && p.current().Tipo != lexer.TOKEN_EOF {
+		p.advance()
+	}
+	p.match(lexer.TOKEN_NEWLINE)
+}
+
+// precedencia segue a de C: "|" liga menos que "&", que liga menos que os
+// deslocamentos, depois soma e subtração e por fim multiplicação, divisão e
+// resto. Os operadores unários ligam mais que todos os binários.
 var precedencia = map[string]int{
-	"+": 1,
-	"-": 1,
-	"*": 2,
-	"/": 2,
+	"|":  1,
+	"&":  2,
+	"<<": 3,
+	">>": 3,
+	"+":  4,
+	"-":  4,
+	"*":  5,
+	"/":  5,
+	"%":  5,
 }
 
-func (p *Parser) ParsePrograma() ([]Instrucao, error) {
-	instrucoes := []Instrucao{}
+// ParsePrograma analisa o programa inteiro. Em caso de erro a análise
+// continua na linha seguinte, e todos os erros encontrados são retornados
+// juntos em uma diag.Lista.
+func (p *Parser) ParsePrograma() (*ast.Program, error) {
+	// Linhas em branco ou só com comentários antes do cabeçalho são ignoradas.
+	for p.match(lexer.TOKEN_NEWLINE) {
+	}
+	programa := &ast.Program{Pos: p.current().Pos}
 
 	if !p.match(lexer.TOKEN_PROGRAMA) {
-		return nil, fmt.Errorf("Esperado 'PROGRAMA'")
+		p.registra(p.erro("Esperado 'PROGRAMA'"))
+	} else if nome := p.current(); !p.match(lexer.TOKEN_LABEL) {
+		p.registra(p.erro("Esperado nome do programa"))
+	} else if programa.Name = nome.Valor; !p.match(lexer.TOKEN_NEWLINE) {
+		p.registra(p.erro("Esperado quebra de linha após label"))
 	}
-	if !p.match(lexer.TOKEN_LABEL) {
-		return nil, fmt.Errorf("Esperado nome do programa")
+	for p.match(lexer.TOKEN_NEWLINE) {
 	}
-	if !p.match(lexer.TOKEN_NEWLINE) {
-		return nil, fmt.Errorf("Esperado quebra de linha após label")
+	for p.current().Tipo == lexer.TOKEN_VARIAVEIS {
+		if err := p.parseVariaveis(programa); err != nil {
+			p.registra(err)
+		}
+		for p.match(lexer.TOKEN_NEWLINE) {
+		}
 	}
 	if !p.match(lexer.TOKEN_INICIO) || !p.match(lexer.TOKEN_NEWLINE) {
-		return nil, fmt.Errorf("Esperado 'INICIO' na linha seguinte")
+		p.registra(p.erro("Esperado 'INICIO' na linha seguinte"))
+	}
+
+	programa.Body = p.parseBloco(lexer.TOKEN_FIM)
+
+	programa.Fim = p.current().Pos
+	if !p.match(lexer.TOKEN_FIM) {
+		p.erros = append(p.erros, diag.Errorf(p.current().Pos, "Esperado 'FIM'"))
+	}
+	return programa, p.erros.Err()
+}
+
+// parseVariaveis lê uma linha "VARIAVEIS A, B = 5, C". O valor inicial é
+// um número, com '-' opcional.
+func (p *Parser) parseVariaveis(programa *ast.Program) error {
+	p.advance()
+	for {
+		if p.current().Tipo != lexer.TOKEN_VAR {
+			return p.erro("Esperado nome da variável")
+		}
+		nome := p.advance()
+		decl := &ast.VarDecl{Pos: nome.Pos, Name: nome.Valor}
+		if p.match(lexer.TOKEN_ATRIB) {
+			inicial, err := p.parseValorInicial()
+			if err != nil {
+				return err
+			}
+			decl.Init = inicial
+		}
+		programa.Vars = append(programa.Vars, decl)
+
+		if !p.match(lexer.TOKEN_VIRGULA) {
+			break
+		}
+	}
+	if !p.match(lexer.TOKEN_NEWLINE) && p.current().Tipo != lexer.TOKEN_EOF {
+		return p.erro("Esperado ',' ou quebra de linha na declaração de variáveis")
 	}
+	return nil
+}
 
-	for p.current().Tipo != lexer.TOKEN_FIM && p.current().Tipo != lexer.TOKEN_EOF {
+func (p *Parser) parseValorInicial() (*ast.Literal, error) {
+	menos := p.current()
+	negativo := menos.Tipo == lexer.TOKEN_OP && menos.Valor == "-"
+	if negativo {
+		p.advance()
+	}
+	tok := p.current()
+	if tok.Tipo != lexer.TOKEN_NUM {
+		return nil, p.erro("Esperado número como valor inicial")
+	}
+	p.advance()
+	// Literais inválidos já foram reportados pelo lexer.
+	valor, _ := lexer.Numero(tok.Valor)
+	if negativo {
+		return &ast.Literal{Pos: menos.Pos, Value: "-" + tok.Valor, Number: -valor}, nil
+	}
+	return &ast.Literal{Pos: tok.Pos, Value: tok.Valor, Number: valor}, nil
+}
+
+// parseBloco lê instruções até encontrar um dos tokens de fim, que não é
+// consumido. Linhas em branco são ignoradas e instruções com erro são
+// registradas e descartadas.
+func (p *Parser) parseBloco(fins ...lexer.TokenType) []ast.Stmt {
+	instrucoes := []ast.Stmt{}
+	for {
+		for p.match(lexer.TOKEN_NEWLINE) {
+		}
+		tipo := p.current().Tipo
+		if tipo == lexer.TOKEN_EOF {
+			return instrucoes
+		}
+		for _, fim := range fins {
+			if tipo == fim {
+				return instrucoes
+			}
+		}
 		inst, err := p.parseInstrucao()
 		if err != nil {
-			return nil, err
+			p.registra(err)
+			continue
 		}
 		instrucoes = append(instrucoes, inst)
 	}
+}
 
-	if !p.match(lexer.TOKEN_FIM) {
-		return nil, fmt.Errorf("Esperado 'FIM'")
+func (p *Parser) parseInstrucao() (ast.Stmt, error) {
+	switch p.current().Tipo {
+	case lexer.TOKEN_SE:
+		return p.parseSe(), nil
+	case lexer.TOKEN_ENQUANTO:
+		return p.parseEnquanto(), nil
+	case lexer.TOKEN_LEIA:
+		return p.parseLeia()
+	case lexer.TOKEN_ESCREVA:
+		return p.parseEscreva()
+	case lexer.TOKEN_SENAO, lexer.TOKEN_FIMSE, lexer.TOKEN_FIMENQUANTO, lexer.TOKEN_FIM:
+		return nil, p.erro("'%s' sem estrutura correspondente", p.current().Valor)
+	case lexer.TOKEN_VARIAVEIS:
+		return nil, p.erro("'VARIAVEIS' deve vir antes de 'INICIO'")
 	}
-	return instrucoes, nil
+	return p.parseAtribuicao()
 }
 
-func (p *Parser) parseInstrucao() (Instrucao, error) {
-	var nome string
+// fechaBloco consome o token que encerra um bloco, que deve estar sozinho na
+// linha.
+func (p *Parser) fechaBloco(tipo lexer.TokenType, msg string) {
+	if !p.match(tipo) {
+		p.erros = append(p.erros, diag.Errorf(p.current().Pos, "%s", msg))
+		return
+	}
+	if !p.match(lexer.TOKEN_NEWLINE) && p.current().Tipo != lexer.TOKEN_EOF {
+		p.registra(p.erro("%s", msg))
+	}
+}
+
+func (p *Parser) parseSe() ast.Stmt {
+	se := &ast.If{Pos: p.advance().Pos}
+	cond, err := p.parseCondicao()
+	if err != nil {
+		p.registra(err)
+	} else if !p.match(lexer.TOKEN_ENTAO) || !p.match(lexer.TOKEN_NEWLINE) {
+		p.registra(p.erro("Esperado 'ENTAO' e quebra de linha após a condição do SE"))
+	}
+	se.Cond = cond
+
+	se.Then = p.parseBloco(lexer.TOKEN_SENAO, lexer.TOKEN_FIMSE)
+
+	if p.match(lexer.TOKEN_SENAO) {
+		if !p.match(lexer.TOKEN_NEWLINE) {
+			p.registra(p.erro("Esperado quebra de linha após 'SENAO'"))
+		}
+		se.Else = p.parseBloco(lexer.TOKEN_FIMSE)
+	}
+
+	p.fechaBloco(lexer.TOKEN_FIMSE, "Esperado 'FIMSE' em linha própria")
+	return se
+}
+
+func (p *Parser) parseEnquanto() ast.Stmt {
+	enquanto := &ast.While{Pos: p.advance().Pos}
+	cond, err := p.parseCondicao()
+	if err != nil {
+		p.registra(err)
+	} else if !p.match(lexer.TOKEN_FACA) || !p.match(lexer.TOKEN_NEWLINE) {
+		p.registra(p.erro("Esperado 'FACA' e quebra de linha após a condição do ENQUANTO"))
+	}
+	enquanto.Cond = cond
+
+	enquanto.Body = p.parseBloco(lexer.TOKEN_FIMENQUANTO)
+
+	p.fechaBloco(lexer.TOKEN_FIMENQUANTO, "Esperado 'FIMENQUANTO' em linha própria")
+	return enquanto
+}
+
+// parseCondicao lê "expr op expr", com op relacional.
+func (p *Parser) parseCondicao() (ast.Expr, error) {
+	esq, err := p.parseExp()
+	if err != nil {
+		return nil, err
+	}
+	if p.current().Tipo != lexer.TOKEN_RELOP {
+		return nil, p.erro("Esperado operador relacional (<, >, ==, !=, <=, >=)")
+	}
+	op := p.advance()
+	dir, err := p.parseExp()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BinaryExpr{Pos: op.Pos, Op: op.Valor, Left: esq, Right: dir}, nil
+}
+
+func (p *Parser) parseAtribuicao() (ast.Stmt, error) {
 	if p.current().Tipo != lexer.TOKEN_VAR {
-		return Instrucao{}, fmt.Errorf("Esperado nome da variável")
+		return nil, p.erro("Esperado nome da variável")
 	}
-	nome = p.advance().Valor
+	nome := p.advance()
 
 	if !p.match(lexer.TOKEN_ATRIB) {
-		return Instrucao{}, fmt.Errorf("Esperado '=' após variável")
+		return nil, p.erro("Esperado '=' após variável")
 	}
 
 	expr, err := p.parseExp()
 	if err != nil {
-		return Instrucao{}, err
+		return nil, err
 	}
 
-	if !p.match(lexer.TOKEN_NEWLINE) {
-		return Instrucao{}, fmt.Errorf("Esperado quebra de linha após expressão")
+	if !p.match(lexer.TOKEN_NEWLINE) && p.current().Tipo != lexer.TOKEN_EOF {
+		return nil, p.erro("Esperado quebra de linha após expressão")
 	}
 
-	return Instrucao{Var: nome, Expr: expr}, nil
+	return &ast.Assign{Pos: nome.Pos, Var: &ast.Ident{Pos: nome.Pos, Name: nome.Valor}, Value: expr}, nil
+}
+
+func (p *Parser) parseLeia() (ast.Stmt, error) {
+	leia := p.advance()
+	if p.current().Tipo != lexer.TOKEN_VAR {
+		return nil, p.erro("Esperado nome da variável após 'LEIA'")
+	}
+	nome := p.advance()
+	if !p.match(lexer.TOKEN_NEWLINE) && p.current().Tipo != lexer.TOKEN_EOF {
+		return nil, p.erro("Esperado quebra de linha após variável")
+	}
+	return &ast.Read{Pos: leia.Pos, Var: &ast.Ident{Pos: nome.Pos, Name: nome.Valor}}, nil
+}
+
+func (p *Parser) parseEscreva() (ast.Stmt, error) {
+	escreva := p.advance()
+	expr, err := p.parseExp()
+	if err != nil {
+		return nil, err
+	}
+	if !p.match(lexer.TOKEN_NEWLINE) && p.current().Tipo != lexer.TOKEN_EOF {
+		return nil, p.erro("Esperado quebra de linha após expressão")
+	}
+	return &ast.Write{Pos: escreva.Pos, Value: expr}, nil
+}
+
+func (p *Parser) parseExp() (ast.Expr, error) {
+	return p.parseBinaria(1)
 }
 
-func (p *Parser) parseExp() ([]lexer.Token, error) {
-	saida := []lexer.Token{}
-	pilha := []lexer.Token{}
+// parseBinaria lê operações binárias por precedência: operadores com
+// precedência menor que minPrec ficam para o nível de cima, e operadores de
+// mesma precedência associam à esquerda.
+func (p *Parser) parseBinaria(minPrec int) (ast.Expr, error) {
+	esq, err := p.parseFator()
+	if err != nil {
+		return nil, err
+	}
 
 	for {
 		tok := p.current()
-		if tok.Tipo == lexer.TOKEN_NUM || tok.Tipo == lexer.TOKEN_VAR {
-			saida = append(saida, tok)
-			p.advance()
-		} else if tok.Tipo == lexer.TOKEN_OP {
-			for len(pilha) > 0 {
-				top := pilha[len(pilha)-1]
-				if top.Tipo == lexer.TOKEN_OP && precedencia[top.Valor] >= precedencia[tok.Valor] {
-					saida = append(saida, top)
-					pilha = pilha[:len(pilha)-1]
-				} else {
-					break
-				}
-			}
-			pilha = append(pilha, tok)
-			p.advance()
-		} else if tok.Tipo == lexer.TOKEN_ABREPAR {
-			pilha = append(pilha, tok)
-			p.advance()
-		} else if tok.Tipo == lexer.TOKEN_FECHAPAR {
-			for len(pilha) > 0 && pilha[len(pilha)-1].Tipo != lexer.TOKEN_ABREPAR {
-				saida = append(saida, pilha[len(pilha)-1])
-				pilha = pilha[:len(pilha)-1]
-			}
-			if len(pilha) == 0 || pilha[len(pilha)-1].Tipo != lexer.TOKEN_ABREPAR {
-				return nil, fmt.Errorf("Parêntese não balanceado")
-			}
-			pilha = pilha[:len(pilha)-1] // descarta o "("
-			p.advance()
-		} else {
-			break
+		prec, ok := precedencia[tok.Valor]
+		if tok.Tipo != lexer.TOKEN_OP || !ok || prec < minPrec {
+			return esq, nil
 		}
-	}
+		p.advance()
 
-	for len(pilha) > 0 {
-		if pilha[len(pilha)-1].Tipo == lexer.TOKEN_ABREPAR {
-			return nil, fmt.Errorf("Parêntese não fechado")
+		dir, err := p.parseBinaria(prec + 1)
+		if err != nil {
+			return nil, err
 		}
-		saida = append(saida, pilha[len(pilha)-1])
-		pilha = pilha[:len(pilha)-1]
+		esq = &ast.BinaryExpr{Pos: tok.Pos, Op: tok.Valor, Left: esq, Right: dir}
 	}
+}
 
-	return saida, nil
+func (p *Parser) parseFator() (ast.Expr, error) {
+	tok := p.current()
+	switch tok.Tipo {
+	case lexer.TOKEN_NUM:
+		p.advance()
+		// Literais inválidos já foram reportados pelo lexer.
+		valor, _ := lexer.Numero(tok.Valor)
+		return &ast.Literal{Pos: tok.Pos, Value: tok.Valor, Number: valor}, nil
+	case lexer.TOKEN_VAR:
+		p.advance()
+		return &ast.Ident{Pos: tok.Pos, Name: tok.Valor}, nil
+	case lexer.TOKEN_ABREPAR:
+		p.advance()
+		expr, err := p.parseExp()
+		if err != nil {
+			return nil, err
+		}
+		if !p.match(lexer.TOKEN_FECHAPAR) {
+			return nil, diag.Errorf(tok.Pos, "Parêntese não fechado")
+		}
+		return expr, nil
+	case lexer.TOKEN_OP:
+		if tok.Valor == "-" || tok.Valor == "~" {
+			p.advance()
+			operando, err := p.parseFator()
+			if err != nil {
+				return nil, err
+			}
+			return &ast.UnaryExpr{Pos: tok.Pos, Op: tok.Valor, Operand: operando}, nil
+		}
+	case lexer.TOKEN_FECHAPAR:
+		return nil, p.erro("Parêntese não balanceado")
+	}
+	return nil, p.erro("Esperada expressão")
 }
\ No newline at end of file
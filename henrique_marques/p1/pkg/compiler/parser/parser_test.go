@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"p1/pkg/compiler/ast"
+	"p1/pkg/compiler/lexer"
+)
+
+func analisa(fonte string) (*ast.Program, error) {
+	tokens, err := lexer.Lex(fonte)
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(tokens).ParsePrograma()
+}
+
+func programa(corpo string) string {
+	return "PROGRAMA \"T\"\nINICIO\n" + corpo + "\nFIM\n"
+}
+
+// arvore escreve a expressão com cada operação entre parênteses, para
+// mostrar como ela foi agrupada.
+func arvore(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		return fmt.Sprint(e.Number)
+	case *ast.Ident:
+		return e.Name
+	case *ast.UnaryExpr:
+		return "(" + e.Op + arvore(e.Operand) + ")"
+	case *ast.BinaryExpr:
+		return "(" + arvore(e.Left) + " " + e.Op + " " + arvore(e.Right) + ")"
+	}
+	return "?"
+}
+
+// instrucoes escreve cada instrução em uma linha, com os blocos entre
+// chaves.
+func instrucoes(corpo []ast.Stmt) string {
+	var partes []string
+	for _, inst := range corpo {
+		switch inst := inst.(type) {
+		case *ast.Assign:
+			partes = append(partes, inst.Var.Name+" = "+arvore(inst.Value))
+		case *ast.Read:
+			partes = append(partes, "LEIA "+inst.Var.Name)
+		case *ast.Write:
+			partes = append(partes, "ESCREVA "+arvore(inst.Value))
+		case *ast.If:
+			partes = append(partes, "SE "+arvore(inst.Cond)+" {"+instrucoes(inst.Then)+"} {"+instrucoes(inst.Else)+"}")
+		case *ast.While:
+			partes = append(partes, "ENQUANTO "+arvore(inst.Cond)+" {"+instrucoes(inst.Body)+"}")
+		}
+	}
+	return strings.Join(partes, "; ")
+}
+
+func TestPrecedencia(t *testing.T) {
+	casos := []struct {
+		expr   string
+		arvore string
+	}{
+		{expr: "1 + 2 * 3", arvore: "(1 + (2 * 3))"},
+		{expr: "A - B - C", arvore: "((A - B) - C)"},
+		{expr: "(A + B) * C", arvore: "((A + B) * C)"},
+		{expr: "A | B & C", arvore: "(A | (B & C))"},
+		{expr: "A << 1 + B", arvore: "(A << (1 + B))"},
+		{expr: "A / B % C", arvore: "((A / B) % C)"},
+		{expr: "-A * ~B", arvore: "((-A) * (~B))"},
+		{expr: "-(A + 1)", arvore: "(-(A + 1))"},
+		{expr: "0x10 + 'A'", arvore: "(16 + 65)"},
+		{expr: "A E B OU C", arvore: "((A & B) | C)"},
+		{expr: "NAO A OU B E C", arvore: "((~A) | (B & C))"},
+	}
+	for _, c := range casos {
+		p, err := analisa(programa("X = " + c.expr))
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if obtida := instrucoes(p.Body); obtida != "X = "+c.arvore {
+			t.Errorf("%s: %s, esperado X = %s", c.expr, obtida, c.arvore)
+		}
+	}
+}
+
+func TestEstruturas(t *testing.T) {
+	fonte := "PROGRAMA \"T\"\n" +
+		"VARIAVEIS A, B = 5\n" +
+		"VARIAVEIS C = -1\n" +
+		"INICIO\n" +
+		"LEIA A\n" +
+		"ENQUANTO A > 0 FACA\n" +
+		"  SE A % 2 == 1 ENTAO\n" +
+		"    ESCREVA A\n" +
+		"  SENAO\n" +
+		"    B = B + 1\n" +
+		"  FIMSE\n" +
+		"  A = A - 1\n" +
+		"FIMENQUANTO\n" +
+		"SE B != 5 ENTAO\n" +
+		"FIMSE\n" +
+		"FIM\n"
+	p, err := analisa(fonte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperadas := "LEIA A; ENQUANTO (A > 0) {SE ((A % 2) == 1) {ESCREVA A} {B = (B + 1)}; A = (A - 1)}; SE (B != 5) {} {}"
+	if obtidas := instrucoes(p.Body); obtidas != esperadas {
+		t.Errorf("instruções:\n%s\nesperadas:\n%s", obtidas, esperadas)
+	}
+
+	var vars []string
+	for _, v := range p.Vars {
+		if v.Init != nil {
+			vars = append(vars, fmt.Sprintf("%s=%d", v.Name, v.Init.Number))
+		} else {
+			vars = append(vars, v.Name)
+		}
+	}
+	if p.Name != "T" || strings.Join(vars, " ") != "A B=5 C=255" {
+		t.Errorf("programa %q, variáveis %v", p.Name, vars)
+	}
+
+	enquanto := p.Body[1].(*ast.While)
+	se := enquanto.Body[0].(*ast.If)
+	if enquanto.Pos.String() != "6:1" || se.Pos.String() != "7:3" || se.Cond.Posicao().String() != "7:12" || p.Fim.String() != "16:1" {
+		t.Errorf("posições: ENQUANTO %s, SE %s, condição %s, FIM %s", enquanto.Pos, se.Pos, se.Cond.Posicao(), p.Fim)
+	}
+}
+
+func TestErrosSintaticos(t *testing.T) {
+	casos := []struct {
+		nome  string
+		fonte string
+		erros []string
+	}{
+		{nome: "sem PROGRAMA", fonte: "\"T\"\nINICIO\nFIM\n", erros: []string{"1:1: Esperado 'PROGRAMA'"}},
+		{nome: "sem FIM", fonte: "PROGRAMA \"T\"\nINICIO\nA = 1\n", erros: []string{"Esperado 'FIM'"}},
+		{nome: "sem '='", fonte: programa("A 1"), erros: []string{"3:3: Esperado '=' após variável"}},
+		{nome: "expressão incompleta", fonte: programa("A = 1 +"), erros: []string{"3:8: Esperada expressão"}},
+		{nome: "parêntese aberto", fonte: programa("A = (1 + 2"), erros: []string{"3:5: Parêntese não fechado"}},
+		{nome: "parêntese a mais", fonte: programa("A = 1 + 2)"), erros: []string{"Esperado quebra de linha após expressão"}},
+		{nome: "condição sem comparação", fonte: programa("SE A ENTAO\nFIMSE"), erros: []string{"Esperado operador relacional"}},
+		{nome: "SE sem FIMSE", fonte: programa("SE A > 1 ENTAO\nA = 1"), erros: []string{"Esperado 'FIMSE' em linha própria"}},
+		{nome: "FIMENQUANTO solto", fonte: programa("FIMENQUANTO"), erros: []string{"3:1: 'FIMENQUANTO' sem estrutura correspondente"}},
+		{nome: "LEIA sem variável", fonte: programa("LEIA 3"), erros: []string{"3:6: Esperado nome da variável após 'LEIA'"}},
+		{nome: "operador como variável", fonte: programa("OU = 1\nLEIA E"), erros: []string{"3:1: Esperado nome da variável", "4:6: Esperado nome da variável após 'LEIA'"}},
+		{nome: "VARIAVEIS depois de INICIO", fonte: programa("VARIAVEIS A"), erros: []string{"'VARIAVEIS' deve vir antes de 'INICIO'"}},
+		{
+			nome:  "continua na linha seguinte",
+			fonte: programa("A = \nB 2\nC = 3\nD = )"),
+			erros: []string{"3:5: Esperada expressão", "4:3: Esperado '=' após variável", "6:5: Parêntese não balanceado"},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			_, err := analisa(c.fonte)
+			if err == nil {
+				t.Fatal("esperado erro")
+			}
+			for _, e := range c.erros {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("erros sem %q:\n%v", e, err)
+				}
+			}
+		})
+	}
+}
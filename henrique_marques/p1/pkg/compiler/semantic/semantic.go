@@ -0,0 +1,151 @@
+package semantic
+
+import (
+	"p1/pkg/compiler/ast"
+	"p1/pkg/compiler/generator"
+	"p1/pkg/diag"
+)
+
+// analise guarda o que já se sabe das variáveis enquanto o programa é
+// percorrido.
+type analise struct {
+	// declaradas são as variáveis do bloco VARIAVEIS; sem o bloco, toda
+	// variável é declarada pelo uso.
+	declaradas map[string]*ast.VarDecl
+	usadas     map[string]bool
+	conferidas map[string]bool
+	avisadas   map[string]bool
+	erros      diag.Lista
+	avisos     diag.Lista
+}
+
+// Analisa confere o uso das variáveis antes da geração de código. São erros:
+//
+//   - com o bloco VARIAVEIS, usar uma variável que não está nele;
+//   - declarar a mesma variável duas vezes;
+//   - dar a uma variável um nome reservado (veja generator.NomeReservado).
+//
+// São avisos, devolvidos à parte porque não impedem a compilação:
+//
+//   - variável declarada e nunca usada;
+//   - variável que pode ser lida antes de receber um valor, e então vale 0.
+//
+// Uma variável recebe valor na declaração, em uma atribuição ou em um LEIA.
+// Depois de um SE, só contam os valores recebidos nos dois caminhos; depois
+// de um ENQUANTO, só os recebidos antes dele, já que o corpo pode não
+// executar.
+func Analisa(programa *ast.Program) (diag.Lista, error) {
+	a := &analise{
+		declaradas: map[string]*ast.VarDecl{},
+		usadas:     map[string]bool{},
+		conferidas: map[string]bool{},
+		avisadas:   map[string]bool{},
+	}
+
+	atribuidas := map[string]bool{}
+	for _, d := range programa.Vars {
+		a.confereNome(d.Pos, d.Name)
+		if anterior, ok := a.declaradas[d.Name]; ok {
+			a.erros.Add(d.Pos, "variável %s já declarada na linha %d", d.Name, anterior.Pos.Linha)
+			continue
+		}
+		a.declaradas[d.Name] = d
+		if d.Init != nil {
+			atribuidas[d.Name] = true
+		}
+	}
+
+	a.instrucoes(programa.Body, atribuidas)
+
+	for _, d := range programa.Vars {
+		_, reservado := generator.NomeReservado(d.Name)
+		if a.declaradas[d.Name] == d && !a.usadas[d.Name] && !reservado {
+			a.avisos.Aviso(d.Pos, "variável %s declarada e não usada", d.Name)
+		}
+	}
+	a.avisos.Ordena()
+	return a.avisos, a.erros.Err()
+}
+
+// instrucoes percorre as instruções, acrescentando a atribuidas as
+// variáveis que com certeza recebem um valor nelas.
+func (a *analise) instrucoes(instrucoes []ast.Stmt, atribuidas map[string]bool) {
+	for _, inst := range instrucoes {
+		switch inst := inst.(type) {
+		case *ast.Assign:
+			a.le(inst.Value, atribuidas)
+			a.escreve(inst.Var, atribuidas)
+		case *ast.Read:
+			a.escreve(inst.Var, atribuidas)
+		case *ast.Write:
+			a.le(inst.Value, atribuidas)
+		case *ast.If:
+			a.le(inst.Cond, atribuidas)
+			entao, senao := copia(atribuidas), copia(atribuidas)
+			a.instrucoes(inst.Then, entao)
+			a.instrucoes(inst.Else, senao)
+			for v := range entao {
+				if senao[v] {
+					atribuidas[v] = true
+				}
+			}
+		case *ast.While:
+			a.le(inst.Cond, atribuidas)
+			a.instrucoes(inst.Body, copia(atribuidas))
+		}
+	}
+}
+
+func (a *analise) le(expr ast.Expr, atribuidas map[string]bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if a.usa(e) && !atribuidas[e.Name] && !a.avisadas[e.Name] {
+			a.avisadas[e.Name] = true
+			a.avisos.Aviso(e.Pos, "variável %s pode ser lida antes de receber um valor (vale 0)", e.Name)
+		}
+	case *ast.UnaryExpr:
+		a.le(e.Operand, atribuidas)
+	case *ast.BinaryExpr:
+		a.le(e.Left, atribuidas)
+		a.le(e.Right, atribuidas)
+	}
+}
+
+func (a *analise) escreve(variavel *ast.Ident, atribuidas map[string]bool) {
+	if a.usa(variavel) {
+		atribuidas[variavel.Name] = true
+	}
+}
+
+// usa registra o uso da variável e informa se ela é válida: declarada, se
+// houver o bloco VARIAVEIS, e com um nome que não é reservado. Os erros
+// apontam para a própria variável, não para a instrução que a usa.
+func (a *analise) usa(variavel *ast.Ident) bool {
+	a.usadas[variavel.Name] = true
+	if len(a.declaradas) > 0 {
+		if a.declaradas[variavel.Name] == nil {
+			a.erros.Add(variavel.Pos, "variável %s não declarada em VARIAVEIS", variavel.Name)
+			return false
+		}
+		return true
+	}
+	return a.confereNome(variavel.Pos, variavel.Name)
+}
+
+// confereNome reporta, uma única vez por nome, o uso de um nome reservado.
+func (a *analise) confereNome(pos diag.Pos, nome string) bool {
+	motivo, reservado := generator.NomeReservado(nome)
+	if reservado && !a.conferidas[nome] {
+		a.erros.Add(pos, "o nome %s é %s e não pode ser usado como variável", nome, motivo)
+	}
+	a.conferidas[nome] = true
+	return !reservado
+}
+
+func copia(conjunto map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(conjunto))
+	for k, v := range conjunto {
+		c[k] = v
+	}
+	return c
+}
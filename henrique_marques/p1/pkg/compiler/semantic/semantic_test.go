@@ -0,0 +1,143 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+
+	"p1/pkg/compiler/lexer"
+	"p1/pkg/compiler/parser"
+	"p1/pkg/diag"
+)
+
+func analisa(t *testing.T, fonte string) (diag.Lista, error) {
+	t.Helper()
+	tokens, err := lexer.Lex(fonte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	programa, err := parser.NewParser(tokens).ParsePrograma()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Analisa(programa)
+}
+
+func TestErrosSemanticos(t *testing.T) {
+	casos := []struct {
+		nome  string
+		fonte string
+		erros []string
+	}{
+		{
+			nome:  "declaração",
+			fonte: "PROGRAMA \"S\"\nVARIAVEIS A, A, TMP1\nINICIO\nA = B\nFIM\n",
+			erros: []string{"2:14: variável A já declarada na linha 2", "2:17: o nome TMP1 é reservado", "4:5: variável B não declarada"},
+		},
+		{
+			nome:  "diretiva",
+			fonte: "PROGRAMA \"S\"\nINICIO\nDB = 1\nFIM\n",
+			erros: []string{"3:1: o nome DB é uma diretiva do assembler"},
+		},
+		{
+			nome:  "instruções do Neander",
+			fonte: "PROGRAMA \"S\"\nVARIAVEIS NOT, ADD = 1\nINICIO\nNOT = ADD\nJMP = 2\nHLT = JMP\nFIM\n",
+			erros: []string{
+				"2:11: o nome NOT é uma instrução do Neander",
+				"2:16: o nome ADD é uma instrução do Neander",
+				"5:1: variável JMP não declarada",
+			},
+		},
+		{
+			nome:  "instruções sem VARIAVEIS",
+			fonte: "PROGRAMA \"S\"\nINICIO\nSTA = 1\nLDA = STA\nFIM\n",
+			erros: []string{"3:1: o nome STA é uma instrução do Neander", "4:1: o nome LDA é uma instrução do Neander"},
+		},
+		{
+			nome:  "instruções do Ahmes",
+			fonte: "PROGRAMA \"S\"\nINICIO\nSUB = 1\nLEIA SHL\nFIM\n",
+			erros: []string{"3:1: o nome SUB é uma instrução do Ahmes", "4:6: o nome SHL é uma instrução do Ahmes"},
+		},
+		{
+			nome:  "posição da variável",
+			fonte: "PROGRAMA \"S\"\nINICIO\nX = A + SHL\n  LEIA ROL\nFIM\n",
+			erros: []string{"3:9: o nome SHL é uma instrução do Ahmes", "4:8: o nome ROL é uma instrução do Ahmes"},
+		},
+		{
+			nome:  "posição da variável não declarada",
+			fonte: "PROGRAMA \"S\"\nVARIAVEIS X\nINICIO\nX = X + Y\nLEIA  Z\nFIM\n",
+			erros: []string{"4:9: variável Y não declarada", "5:7: variável Z não declarada"},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			_, err := analisa(t, c.fonte)
+			if err == nil {
+				t.Fatal("esperados erros semânticos")
+			}
+			for _, msg := range c.erros {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("esperado erro %q em:\n%v", msg, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSemErros(t *testing.T) {
+	for _, fonte := range []string{
+		"PROGRAMA \"S\"\nINICIO\nA = 1\nB = A + 1\nFIM\n",
+		"PROGRAMA \"S\"\nVARIAVEIS A = 1, B\nINICIO\nB = A\nFIM\n",
+		// Nomes parecidos com instruções, mas diferentes, são aceitos.
+		"PROGRAMA \"S\"\nINICIO\nNOTA = 1\nTMP = NOTA\nFIM\n",
+	} {
+		avisos, err := analisa(t, fonte)
+		if err != nil || len(avisos) > 0 {
+			t.Errorf("%q: avisos %v, erro %v", fonte, avisos, err)
+		}
+	}
+}
+
+func TestAvisos(t *testing.T) {
+	casos := []struct {
+		nome   string
+		fonte  string
+		avisos []string
+	}{
+		{
+			nome:  "declarada e lida antes de escrita",
+			fonte: "PROGRAMA \"S\"\nVARIAVEIS A, B, X\nINICIO\nSE A > 1 ENTAO\n  B = 1\nFIMSE\nA = B\nFIM\n",
+			avisos: []string{
+				"2:17: variável X declarada e não usada",
+				"4:4: variável A pode ser lida antes de receber um valor (vale 0)",
+				"7:5: variável B pode ser lida antes de receber um valor (vale 0)",
+			},
+		},
+		{
+			nome:   "valor nos dois caminhos do SE",
+			fonte:  "PROGRAMA \"S\"\nINICIO\nLEIA A\nSE A > 1 ENTAO\n  B = 1\nSENAO\n  B = 2\nFIMSE\nESCREVA B\nFIM\n",
+			avisos: nil,
+		},
+		{
+			nome:   "valor só dentro do ENQUANTO",
+			fonte:  "PROGRAMA \"S\"\nINICIO\nLEIA A\nENQUANTO A > 1 FACA\n  B = 1\n  A = A - 1\nFIMENQUANTO\nESCREVA B\nFIM\n",
+			avisos: []string{"8:9: variável B pode ser lida antes de receber um valor (vale 0)"},
+		},
+		{
+			nome:   "nome reservado não é avisado como não usado",
+			fonte:  "PROGRAMA \"S\"\nVARIAVEIS NOT\nINICIO\nFIM\n",
+			avisos: nil,
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			avisos, _ := analisa(t, c.fonte)
+			var msgs []string
+			for _, a := range avisos {
+				msgs = append(msgs, a.Error())
+			}
+			if strings.Join(msgs, "\n") != strings.Join(c.avisos, "\n") {
+				t.Errorf("avisos:\n%s\nesperados:\n%s", strings.Join(msgs, "\n"), strings.Join(c.avisos, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"p1/pkg/encoder"
+)
+
+// limitePassos evita que "continue", "next" e "run" travem o depurador em
+// programas que nunca chegam a um HLT.
+const limitePassos = 100000
+
+type Debugger struct {
+	CPU         *encoder.CPU
+	Labels      map[string]uint8
+	Breakpoints map[uint8]bool
+
+	out io.Writer
+}
+
+func NewDebugger(cpu *encoder.CPU, labels map[string]uint8, out io.Writer) *Debugger {
+	if labels == nil {
+		labels = map[string]uint8{}
+	}
+	return &Debugger{
+		CPU:         cpu,
+		Labels:      labels,
+		Breakpoints: map[uint8]bool{},
+		out:         out,
+	}
+}
+
+// Run lê comandos de in até "quit" ou fim da entrada.
+func (d *Debugger) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	d.mostraInstrucao()
+	for {
+		fmt.Fprint(d.out, "(neander) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(d.out)
+			return
+		}
+		campos := strings.Fields(scanner.Text())
+		if len(campos) == 0 {
+			continue
+		}
+		if campos[0] == "q" || campos[0] == "quit" {
+			return
+		}
+		if err := d.Exec(campos[0], campos[1:]); err != nil {
+			fmt.Fprintf(d.out, "erro: %v\n", err)
+		}
+	}
+}
+
+// Exec executa um único comando do depurador.
+func (d *Debugger) Exec(cmd string, args []string) error {
+	switch cmd {
+	case "h", "help":
+		d.ajuda()
+	case "b", "break":
+		if len(args) != 1 {
+			return fmt.Errorf("uso: break <endereço|rótulo>")
+		}
+		addr, err := d.endereco(args[0])
+		if err != nil {
+			return err
+		}
+		d.Breakpoints[addr] = true
+		fmt.Fprintf(d.out, "breakpoint em %02X\n", addr)
+	case "d", "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("uso: delete <endereço|rótulo>")
+		}
+		addr, err := d.endereco(args[0])
+		if err != nil {
+			return err
+		}
+		if !d.Breakpoints[addr] {
+			return fmt.Errorf("não há breakpoint em %02X", addr)
+		}
+		delete(d.Breakpoints, addr)
+	case "l", "list":
+		d.listaBreakpoints()
+	case "s", "step":
+		if err := d.CPU.Step(); err != nil {
+			return err
+		}
+		d.mostraInstrucao()
+	case "n", "next":
+		if d.parado() {
+			return nil
+		}
+		op := d.CPU.Memory[d.CPU.PC]
+		proxima := d.CPU.PC + encoder.InstructionSize(op, d.CPU.Ahmes)
+		if err := d.CPU.Step(); err != nil {
+			return err
+		}
+		return d.executa(func() bool { return d.CPU.PC == proxima }, true)
+	case "c", "continue":
+		if d.parado() {
+			return nil
+		}
+		if err := d.CPU.Step(); err != nil {
+			return err
+		}
+		return d.executa(func() bool { return false }, true)
+	case "reset":
+		d.CPU.Reset()
+		d.mostraInstrucao()
+	case "run":
+		if d.parado() {
+			return nil
+		}
+		return d.executa(func() bool { return false }, false)
+	case "r", "regs":
+		d.mostraRegistradores()
+	case "x", "mem":
+		return d.examina(args)
+	case "m", "poke":
+		if len(args) != 2 {
+			return fmt.Errorf("uso: poke <endereço|rótulo> <valor>")
+		}
+		addr, err := d.endereco(args[0])
+		if err != nil {
+			return err
+		}
+		valor, err := parseByte(args[1])
+		if err != nil {
+			return err
+		}
+		d.CPU.Memory[addr] = valor
+	case "set":
+		return d.altera(args)
+	default:
+		return fmt.Errorf("comando desconhecido: %s (digite help)", cmd)
+	}
+	return nil
+}
+
+// parado avisa, se a CPU já executou o HLT, que continue, next e run só
+// voltam a executar depois de um reset.
+func (d *Debugger) parado() bool {
+	if d.CPU.Halted {
+		fmt.Fprintln(d.out, "programa parado em HLT; use reset")
+	}
+	return d.CPU.Halted
+}
+
+// executa roda a CPU até HLT, até parar() ser verdadeiro ou, se
+// usaBreakpoints, até atingir um breakpoint. Um erro da CPU interrompe a
+// execução e é devolvido.
+func (d *Debugger) executa(parar func() bool, usaBreakpoints bool) error {
+	for passos := 0; ; passos++ {
+		if d.CPU.Halted || d.CPU.Memory[d.CPU.PC] == encoder.HLT {
+			fmt.Fprintln(d.out, "programa parado em HLT")
+			break
+		}
+		if parar() {
+			break
+		}
+		if usaBreakpoints && d.Breakpoints[d.CPU.PC] {
+			fmt.Fprintf(d.out, "breakpoint atingido em %02X\n", d.CPU.PC)
+			break
+		}
+		if passos >= limitePassos {
+			fmt.Fprintf(d.out, "interrompido após %d instruções\n", limitePassos)
+			break
+		}
+		if err := d.CPU.Step(); err != nil {
+			d.mostraInstrucao()
+			return err
+		}
+	}
+	d.mostraInstrucao()
+	return nil
+}
+
+func (d *Debugger) altera(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("uso: set <ac|pc|n|z|v|c|b> <valor>")
+	}
+	switch strings.ToLower(args[0]) {
+	case "ac":
+		valor, err := parseByte(args[1])
+		if err != nil {
+			return err
+		}
+		d.CPU.AC = valor
+	case "pc":
+		addr, err := d.endereco(args[1])
+		if err != nil {
+			return err
+		}
+		d.CPU.PC = addr
+		d.CPU.Halted = false
+	case "n", "z", "v", "c", "b":
+		flag, err := strconv.ParseBool(args[1])
+		if err != nil {
+			return fmt.Errorf("valor de flag inválido: %s (use 0/1 ou true/false)", args[1])
+		}
+		switch strings.ToLower(args[0]) {
+		case "n":
+			d.CPU.N = flag
+		case "z":
+			d.CPU.Z = flag
+		case "v":
+			d.CPU.V = flag
+		case "c":
+			d.CPU.C = flag
+		case "b":
+			d.CPU.B = flag
+		}
+	default:
+		return fmt.Errorf("registrador desconhecido: %s", args[0])
+	}
+	d.mostraRegistradores()
+	return nil
+}
+
+func (d *Debugger) examina(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("uso: mem <endereço|rótulo> [quantidade]")
+	}
+	addr, err := d.endereco(args[0])
+	if err != nil {
+		return err
+	}
+	qtd := 1
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("quantidade inválida: %s", args[1])
+		}
+		qtd = n
+	}
+	for i := 0; i < qtd && int(addr)+i < encoder.MEM_SIZE; i++ {
+		if i%8 == 0 {
+			if i > 0 {
+				fmt.Fprintln(d.out)
+			}
+			fmt.Fprintf(d.out, "%02X:", int(addr)+i)
+		}
+		fmt.Fprintf(d.out, " %02X", d.CPU.Memory[int(addr)+i])
+	}
+	fmt.Fprintln(d.out)
+	return nil
+}
+
+func (d *Debugger) mostraRegistradores() {
+	fmt.Fprintf(d.out, "AC: %02X PC: %02X N: %t Z: %t", d.CPU.AC, d.CPU.PC, d.CPU.N, d.CPU.Z)
+	if d.CPU.Ahmes {
+		fmt.Fprintf(d.out, " V: %t C: %t B: %t", d.CPU.V, d.CPU.C, d.CPU.B)
+	}
+	fmt.Fprintf(d.out, " (instruções: %d, acessos: %d)\n", d.CPU.Instructions, d.CPU.Accesses())
+}
+
+func (d *Debugger) mostraInstrucao() {
+	op := d.CPU.Memory[d.CPU.PC]
+	nome, ok := encoder.Mnemonico(op, d.CPU.Ahmes)
+	if !ok {
+		nome = fmt.Sprintf("?? (%02X)", op)
+	}
+	if encoder.InstructionSize(op, d.CPU.Ahmes) == 2 {
+		operando := d.CPU.Memory[d.CPU.PC+1]
+		nome = fmt.Sprintf("%s %02X%s", nome, operando, d.rotuloDe(operando))
+	}
+	fmt.Fprintf(d.out, "%02X%s: %s\n", d.CPU.PC, d.rotuloDe(d.CPU.PC), nome)
+}
+
+func (d *Debugger) rotuloDe(addr uint8) string {
+	rotulo := ""
+	for nome, a := range d.Labels {
+		if a == addr && (rotulo == "" || nome < rotulo) {
+			rotulo = nome
+		}
+	}
+	if rotulo == "" {
+		return ""
+	}
+	return " <" + rotulo + ">"
+}
+
+func (d *Debugger) listaBreakpoints() {
+	if len(d.Breakpoints) == 0 {
+		fmt.Fprintln(d.out, "nenhum breakpoint definido")
+		return
+	}
+	enderecos := []int{}
+	for addr := range d.Breakpoints {
+		enderecos = append(enderecos, int(addr))
+	}
+	sort.Ints(enderecos)
+	for _, addr := range enderecos {
+		fmt.Fprintf(d.out, "%02X%s\n", addr, d.rotuloDe(uint8(addr)))
+	}
+}
+
+func (d *Debugger) ajuda() {
+	fmt.Fprint(d.out, `comandos:
+  break <end|rótulo>    (b) define breakpoint
+  delete <end|rótulo>   (d) remove breakpoint
+  list                  (l) lista breakpoints
+  step                  (s) executa uma instrução
+  next                  (n) executa até a instrução seguinte (passa por laços)
+  continue              (c) executa até breakpoint ou HLT
+  run                   executa até HLT ignorando breakpoints
+  reset                 recarrega a memória e zera os registradores
+  regs                  (r) mostra AC, PC e flags
+  set <reg|flag> <v>    altera ac, pc ou as flags n, z, v, c, b
+  mem <end|rótulo> [n]  (x) mostra n posições de memória
+  poke <end|rótulo> <v> (m) altera uma posição de memória
+  quit                  (q) sai
+`)
+}
+
+// endereco aceita um rótulo conhecido ou um endereço hexadecimal.
+func (d *Debugger) endereco(s string) (uint8, error) {
+	if addr, ok := d.Labels[s]; ok {
+		return addr, nil
+	}
+	addr, err := parseByte(s)
+	if err != nil {
+		return 0, fmt.Errorf("endereço ou rótulo inválido: %s", s)
+	}
+	return addr, nil
+}
+
+func parseByte(s string) (uint8, error) {
+	v, err := strconv.ParseUint(s, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("valor hexadecimal inválido: %s", s)
+	}
+	return uint8(v), nil
+}
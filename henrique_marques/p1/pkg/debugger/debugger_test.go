@@ -0,0 +1,139 @@
+package debugger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"p1/pkg/encoder"
+)
+
+// imagem monta um .mem do Neander com as palavras dadas a partir do
+// endereço 0.
+func imagem(palavras ...uint8) []byte {
+	img := make([]byte, encoder.TOTAL_SIZE)
+	copy(img, []byte{0x03, 0x4E, 0x44, 0x52})
+	for i, p := range palavras {
+		img[encoder.HEADER_SIZE+i*2] = p
+	}
+	return img
+}
+
+// soma: A (0A) + B (0B) em R (0C), com os rótulos INICIO, FIM, A, B e R.
+var soma = imagem(
+	encoder.LDA, 0x0A,
+	encoder.ADD, 0x0B,
+	encoder.STA, 0x0C,
+	encoder.HLT,
+	0, 0, 0,
+	0x05, 0x03, 0x00,
+)
+
+var rotulosSoma = map[string]uint8{"INICIO": 0x00, "FIM": 0x06, "A": 0x0A, "B": 0x0B, "R": 0x0C}
+
+func novo(t *testing.T, img []byte) (*Debugger, *bytes.Buffer) {
+	t.Helper()
+	cpu := encoder.NewCPU()
+	if err := cpu.Load(img); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	return NewDebugger(cpu, rotulosSoma, &out), &out
+}
+
+func TestComandos(t *testing.T) {
+	casos := []struct {
+		nome     string
+		comandos []string
+		pc       uint8
+		ac       uint8
+		saida    string
+		erro     string
+	}{
+		{nome: "step", comandos: []string{"step"}, pc: 0x02, ac: 0x05, saida: "02: ADD 0B <B>"},
+		{nome: "continue até HLT", comandos: []string{"continue"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT"},
+		{nome: "breakpoint por rótulo", comandos: []string{"break FIM", "continue"}, pc: 0x06, ac: 0x08, saida: "breakpoint em 06"},
+		{nome: "breakpoint por endereço", comandos: []string{"b 04", "c"}, pc: 0x04, ac: 0x08, saida: "breakpoint atingido em 04"},
+		{nome: "run ignora breakpoints", comandos: []string{"b 04", "run"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT"},
+		{nome: "next", comandos: []string{"next"}, pc: 0x02, ac: 0x05},
+		{nome: "reset", comandos: []string{"s", "s", "reset"}, pc: 0x00, ac: 0x00},
+		{nome: "set ac", comandos: []string{"set ac 7F"}, ac: 0x7F, saida: "AC: 7F"},
+		{nome: "poke e mem", comandos: []string{"poke A 10", "mem A 3"}, saida: "0A: 10 03 00"},
+		{nome: "delete sem breakpoint", comandos: []string{"delete 04"}, erro: "não há breakpoint em 04"},
+		{nome: "rótulo desconhecido", comandos: []string{"break XYZ"}, erro: "endereço ou rótulo inválido: XYZ"},
+		{nome: "valor inválido", comandos: []string{"set ac 1FF"}, erro: "valor hexadecimal inválido: 1FF"},
+		{nome: "flag inválida", comandos: []string{"set z talvez"}, erro: "valor de flag inválido"},
+		{nome: "comando desconhecido", comandos: []string{"pula"}, erro: "comando desconhecido: pula"},
+		{nome: "step após HLT", comandos: []string{"run", "step", "step"}, pc: 0x06, ac: 0x08, erro: encoder.ErrHalted.Error()},
+		{nome: "continue após HLT", comandos: []string{"run", "step", "continue"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT; use reset"},
+		{nome: "next após HLT", comandos: []string{"run", "step", "next"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT; use reset"},
+		{nome: "run após HLT", comandos: []string{"run", "step", "run"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT; use reset"},
+		{nome: "continue depois de reset", comandos: []string{"run", "step", "reset", "continue"}, pc: 0x06, ac: 0x08, saida: "programa parado em HLT"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			d, out := novo(t, soma)
+			var err error
+			for _, linha := range c.comandos {
+				campos := strings.Fields(linha)
+				if err = d.Exec(campos[0], campos[1:]); err != nil {
+					break
+				}
+			}
+			switch {
+			case c.erro == "" && err != nil:
+				t.Fatalf("erro inesperado: %v", err)
+			case c.erro != "" && (err == nil || !strings.Contains(err.Error(), c.erro)):
+				t.Fatalf("erro %v, esperado %q", err, c.erro)
+			}
+			if d.CPU.PC != c.pc || d.CPU.AC != c.ac {
+				t.Errorf("PC=%02X AC=%02X, esperado PC=%02X AC=%02X", d.CPU.PC, d.CPU.AC, c.pc, c.ac)
+			}
+			if !strings.Contains(out.String(), c.saida) {
+				t.Errorf("saída sem %q:\n%s", c.saida, out.String())
+			}
+		})
+	}
+}
+
+// Um erro da CPU no meio de continue, next ou run interrompe a execução e
+// chega a quem chamou o comando. O JMP inicial faz o LDA da entrada, que
+// está vazia, cair dentro de executa nos três comandos.
+func TestErroDaCPU(t *testing.T) {
+	leitura := imagem(
+		encoder.JMP, 0x04,
+		encoder.HLT,
+		0,
+		encoder.LDA, encoder.IO_IN,
+		encoder.JMP, 0x02,
+	)
+	for _, cmd := range []string{"continue", "next", "run"} {
+		t.Run(cmd, func(t *testing.T) {
+			d, out := novo(t, leitura)
+			d.CPU.IO = encoder.NewDevice(strings.NewReader(""), io.Discard)
+			err := d.Exec(cmd, nil)
+			var erroES *encoder.IOError
+			if !errors.As(err, &erroES) {
+				t.Fatalf("erro %v, esperado *encoder.IOError", err)
+			}
+			if strings.Contains(out.String(), "HLT") {
+				t.Errorf("execução continuou depois do erro:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	d, out := novo(t, soma)
+	d.Run(strings.NewReader("b 04\nc\nr\nsemcomando\nq\nstep\n"))
+	for _, trecho := range []string{"breakpoint atingido em 04", "AC: 08", "erro: comando desconhecido: semcomando"} {
+		if !strings.Contains(out.String(), trecho) {
+			t.Errorf("saída sem %q:\n%s", trecho, out.String())
+		}
+	}
+	if d.CPU.PC != 0x04 {
+		t.Error("comando depois de quit foi executado")
+	}
+}
@@ -0,0 +1,157 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pos é uma posição no código-fonte. Linha e Coluna começam em 1; a coluna
+// conta caracteres, não bytes.
+type Pos struct {
+	Linha  int
+	Coluna int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Linha, p.Coluna)
+}
+
+// Erro é um problema encontrado em uma posição do código-fonte. Com Aviso,
+// é só um alerta, que não impede a compilação.
+type Erro struct {
+	Pos   Pos
+	Msg   string
+	Aviso bool
+}
+
+func (e *Erro) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Errorf cria um *Erro na posição pos.
+func Errorf(pos Pos, format string, args ...any) *Erro {
+	return &Erro{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Avisof cria um aviso na posição pos.
+func Avisof(pos Pos, format string, args ...any) *Erro {
+	return &Erro{Pos: pos, Msg: fmt.Sprintf(format, args...), Aviso: true}
+}
+
+// Lista acumula os erros de uma etapa para que todos sejam reportados de
+// uma vez.
+type Lista []*Erro
+
+func (l *Lista) Add(pos Pos, format string, args ...any) {
+	*l = append(*l, Errorf(pos, format, args...))
+}
+
+// Aviso acrescenta um aviso à lista.
+func (l *Lista) Aviso(pos Pos, format string, args ...any) {
+	*l = append(*l, Avisof(pos, format, args...))
+}
+
+func (l Lista) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err retorna nil se a lista estiver vazia.
+func (l Lista) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Junta reúne os erros de várias etapas em uma única Lista ordenada por
+// posição, sem repetições. Erros nulos são ignorados; se nenhum sobrar,
+// retorna nil.
+func Junta(errs ...error) error {
+	var todos Lista
+	vistos := map[Erro]bool{}
+	add := func(e *Erro) {
+		if !vistos[*e] {
+			vistos[*e] = true
+			todos = append(todos, e)
+		}
+	}
+	for _, err := range errs {
+		var lista Lista
+		var e *Erro
+		switch {
+		case err == nil:
+		case errors.As(err, &lista):
+			for _, e := range lista {
+				add(e)
+			}
+		case errors.As(err, &e):
+			add(e)
+		default:
+			add(&Erro{Msg: err.Error()})
+		}
+	}
+	todos.Ordena()
+	return todos.Err()
+}
+
+// Ordena põe a lista em ordem de posição, mantendo a ordem dos erros na
+// mesma posição.
+func (l Lista) Ordena() {
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Pos.Linha != l[j].Pos.Linha {
+			return l[i].Pos.Linha < l[j].Pos.Linha
+		}
+		return l[i].Pos.Coluna < l[j].Pos.Coluna
+	})
+}
+
+// Format escreve err no estilo do GCC ("arquivo:linha:coluna: error: msg",
+// ou "warning:" para avisos), seguido da linha do código-fonte e de um '^'
+// sob a coluna do erro. Erros sem posição são escritos como "arquivo: error:
+// msg".
+func Format(arquivo, fonte string, err error) string {
+	var lista Lista
+	var e *Erro
+	switch {
+	case errors.As(err, &lista):
+	case errors.As(err, &e):
+		lista = Lista{e}
+	default:
+		return fmt.Sprintf("%s: error: %v\n", arquivo, err)
+	}
+
+	linhas := strings.Split(strings.ReplaceAll(fonte, "\r\n", "\n"), "\n")
+	var sb strings.Builder
+	for _, e := range lista {
+		tipo := "error"
+		if e.Aviso {
+			tipo = "warning"
+		}
+		if e.Pos.Linha < 1 {
+			fmt.Fprintf(&sb, "%s: %s: %s\n", arquivo, tipo, e.Msg)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d: %s: %s\n", arquivo, e.Pos.Linha, e.Pos.Coluna, tipo, e.Msg)
+		if e.Pos.Linha > len(linhas) {
+			continue
+		}
+		linha := []rune(linhas[e.Pos.Linha-1])
+		sb.WriteString(string(linha))
+		sb.WriteByte('\n')
+		for i := 0; i < e.Pos.Coluna-1 && i < len(linha); i++ {
+			if linha[i] == '\t' {
+				sb.WriteByte('\t')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString("^\n")
+	}
+	return sb.String()
+}
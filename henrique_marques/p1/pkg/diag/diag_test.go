@@ -0,0 +1,91 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestJunta(t *testing.T) {
+	var lexico, sintatico Lista
+	lexico.Add(Pos{3, 1}, "c")
+	lexico.Add(Pos{1, 5}, "b")
+	sintatico.Add(Pos{1, 5}, "b") // repetido em outra etapa
+	sintatico.Aviso(Pos{1, 2}, "a")
+
+	casos := []struct {
+		nome  string
+		errs  []error
+		texto string
+	}{
+		{nome: "sem erros", errs: []error{nil, Lista{}.Err()}},
+		{nome: "ordena e remove repetidos", errs: []error{lexico, nil, sintatico}, texto: "1:2: a\n1:5: b\n3:1: c"},
+		{nome: "erro isolado", errs: []error{Errorf(Pos{2, 2}, "x %d", 1)}, texto: "2:2: x 1"},
+		{nome: "erro sem posição", errs: []error{errors.New("arquivo vazio")}, texto: "0:0: arquivo vazio"},
+		{nome: "lista embrulhada", errs: []error{fmt.Errorf("etapa: %w", lexico)}, texto: "1:5: b\n3:1: c"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			err := Junta(c.errs...)
+			if c.texto == "" {
+				if err != nil {
+					t.Fatalf("erro %v, esperado nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.texto {
+				t.Errorf("Junta:\n%v\nesperado:\n%s", err, c.texto)
+			}
+		})
+	}
+}
+
+func TestOrdenaEstavel(t *testing.T) {
+	l := Lista{Errorf(Pos{2, 1}, "segundo"), Errorf(Pos{1, 1}, "primeiro"), Errorf(Pos{2, 1}, "terceiro")}
+	l.Ordena()
+	if l.Error() != "1:1: primeiro\n2:1: segundo\n2:1: terceiro" {
+		t.Errorf("ordem: %s", l)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	fonte := "A = 1\r\n\tB = C + @\nD"
+	var l Lista
+	l.Add(Pos{2, 10}, "caractere inesperado: @")
+	l.Aviso(Pos{1, 1}, "variável A nunca é lida")
+	l.Add(Pos{9, 1}, "fim inesperado")
+	l.Add(Pos{}, "sem posição")
+
+	casos := []struct {
+		nome  string
+		err   error
+		texto string
+	}{
+		{
+			nome: "lista",
+			err:  l,
+			texto: "p.ldh:2:10: error: caractere inesperado: @\n\tB = C + @\n\t        ^\n" +
+				"p.ldh:1:1: warning: variável A nunca é lida\nA = 1\n^\n" +
+				"p.ldh:9:1: error: fim inesperado\n" +
+				"p.ldh: error: sem posição\n",
+		},
+		{nome: "erro isolado", err: Errorf(Pos{3, 1}, "x"), texto: "p.ldh:3:1: error: x\nD\n^\n"},
+		{nome: "erro comum", err: errors.New("permissão negada"), texto: "p.ldh: error: permissão negada\n"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if texto := Format("p.ldh", fonte, c.err); texto != c.texto {
+				t.Errorf("Format:\n%s\nesperado:\n%s", texto, c.texto)
+			}
+		})
+	}
+}
+
+// A coluna conta caracteres: o '^' fica sob o caractere mesmo depois de
+// acentos.
+func TestFormatColunaEmCaracteres(t *testing.T) {
+	texto := Format("p.ldh", "ação = $", Errorf(Pos{1, 8}, "caractere inesperado"))
+	if texto != "p.ldh:1:8: error: caractere inesperado\nação = $\n       ^\n" {
+		t.Errorf("Format:\n%s", texto)
+	}
+}
@@ -0,0 +1,214 @@
+package disassembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"p1/pkg/assembler"
+	"p1/pkg/assembler/lexer"
+	"p1/pkg/encoder"
+)
+
+// Tipos de cada palavra da memória depois da análise de alcance.
+const (
+	livre     = iota // não alcançada nem referenciada
+	instrucao        // início de instrução alcançada a partir do PC inicial
+	operando         // palavra de endereço de uma instrução
+	dado             // referenciada por uma instrução ou com valor diferente de zero
+)
+
+type instr struct {
+	nome        string
+	temOperando bool
+	desvio      bool // JMP e desvios condicionais
+}
+
+type Disassembler struct {
+	Memory [encoder.MEM_SIZE]uint8
+	Ahmes  bool
+
+	tabela  map[uint8]instr
+	tipo    [encoder.MEM_SIZE]int
+	rotulos map[uint8]string
+}
+
+// NewDisassembler carrega uma imagem .mem. O cabeçalho "AHM" liga o
+// conjunto de instruções do Ahmes.
+func NewDisassembler(imagem []byte) (*Disassembler, error) {
+	cpu := encoder.NewCPU()
+	if err := cpu.Load(imagem); err != nil {
+		return nil, err
+	}
+	d := &Disassembler{Memory: cpu.Memory, Ahmes: cpu.Ahmes}
+
+	// A tabela é a mesma usada pelo assembler, invertida.
+	d.tabela = map[uint8]instr{}
+	adiciona := func(tabela map[string]uint8) {
+		for nome, op := range tabela {
+			d.tabela[op] = instr{
+				nome:        nome,
+				temOperando: !assembler.SemOperando[nome],
+				desvio:      strings.HasPrefix(nome, "J"),
+			}
+		}
+	}
+	adiciona(assembler.Instructions)
+	if d.Ahmes {
+		adiciona(assembler.AhmesInstructions)
+	}
+	return d, nil
+}
+
+// Disassemble gera um .asm que, montado novamente, produz a mesma imagem.
+func (d *Disassembler) Disassemble() string {
+	d.analisa()
+
+	var sb strings.Builder
+	sb.WriteString("; Desmontado de uma imagem .mem\n")
+	if d.Ahmes {
+		sb.WriteString(".AHMES\n")
+	}
+	sb.WriteString("\n.CODE\n")
+	d.escreveCodigo(&sb)
+	sb.WriteString("\n.DATA\n")
+	d.escreveDados(&sb)
+	return sb.String()
+}
+
+// analisa separa código de dados percorrendo todos os caminhos possíveis a
+// partir do endereço 00, onde a CPU começa a execução. Palavras que nunca
+// são executadas mas são usadas como operando, ou que não são zero, viram
+// dados.
+func (d *Disassembler) analisa() {
+	d.tipo = [encoder.MEM_SIZE]int{}
+	pendentes := []uint8{0}
+	for len(pendentes) > 0 {
+		pc := pendentes[len(pendentes)-1]
+		pendentes = pendentes[:len(pendentes)-1]
+
+		for d.tipo[pc] == livre {
+			op := d.Memory[pc]
+			in, ok := d.tabela[op]
+			d.tipo[pc] = instrucao
+			if !ok {
+				// Opcode desconhecido: a CPU o trata como NOP.
+				pc++
+				continue
+			}
+			if in.nome == "HLT" {
+				break
+			}
+			if !in.temOperando {
+				pc++
+				continue
+			}
+			if d.tipo[pc+1] != livre {
+				break
+			}
+			d.tipo[pc+1] = operando
+			alvo := d.Memory[pc+1]
+			if in.desvio {
+				pendentes = append(pendentes, alvo)
+				if in.nome == "JMP" {
+					break
+				}
+			}
+			pc += 2
+		}
+	}
+
+	// Operandos que apontam para uma instrução ganham um rótulo L_xx; os que
+	// apontam para o operando de outra instrução (código automodificável)
+	// usam o rótulo dela mais um.
+	d.rotulos = map[uint8]string{}
+	for addr := 0; addr < encoder.MEM_SIZE; addr++ {
+		if d.tipo[addr] != operando {
+			continue
+		}
+		alvo := d.Memory[addr]
+		switch d.tipo[alvo] {
+		case instrucao:
+			d.rotulos[alvo] = fmt.Sprintf("L_%02X", alvo)
+		case operando:
+			d.rotulos[alvo-1] = fmt.Sprintf("L_%02X", alvo-1)
+		case livre:
+			d.tipo[alvo] = dado
+		}
+	}
+	for addr := 0; addr < encoder.MEM_SIZE; addr++ {
+		if d.tipo[addr] == livre && d.Memory[addr] != 0 {
+			d.tipo[addr] = dado
+		}
+		if d.tipo[addr] == dado {
+			d.rotulos[uint8(addr)] = fmt.Sprintf("D_%02X", addr)
+		}
+	}
+}
+
+// escreveCodigo escreve as instruções alcançadas, com um ORG no início de
+// cada trecho contínuo.
+func (d *Disassembler) escreveCodigo(sb *strings.Builder) {
+	proximo := -1
+	for addr := 0; addr < encoder.MEM_SIZE; addr++ {
+		if d.tipo[addr] != instrucao {
+			continue
+		}
+		if addr != proximo {
+			fmt.Fprintf(sb, "ORG %02X\n", addr)
+		}
+		if rotulo, ok := d.rotulos[uint8(addr)]; ok {
+			fmt.Fprintf(sb, "%s:\n", rotulo)
+		}
+
+		op := d.Memory[addr]
+		in, ok := d.tabela[op]
+		switch {
+		case !ok:
+			fmt.Fprintf(sb, "    %-16s; %02X: opcode desconhecido\n", lexer.Hex(op), addr)
+			proximo = addr + 1
+		case !in.temOperando:
+			fmt.Fprintf(sb, "    %-16s; %02X\n", in.nome, addr)
+			proximo = addr + 1
+		case addr+1 >= encoder.MEM_SIZE || d.tipo[addr+1] != operando:
+			// A palavra seguinte já é código (desvio para o meio da
+			// instrução): grava o opcode como número.
+			fmt.Fprintf(sb, "    %-16s; %02X: %s sem operando\n", lexer.Hex(op), addr, in.nome)
+			proximo = addr + 1
+		default:
+			fmt.Fprintf(sb, "    %-16s; %02X\n", in.nome+" "+d.referencia(d.Memory[addr+1]), addr)
+			proximo = addr + 2
+		}
+	}
+}
+
+// escreveDados escreve um rótulo com DB para cada palavra de dados.
+func (d *Disassembler) escreveDados(sb *strings.Builder) {
+	enderecos := []int{}
+	for addr := range d.rotulos {
+		if d.tipo[addr] == dado {
+			enderecos = append(enderecos, int(addr))
+		}
+	}
+	sort.Ints(enderecos)
+
+	proximo := -1
+	for _, addr := range enderecos {
+		if addr != proximo {
+			fmt.Fprintf(sb, "ORG %02X\n", addr)
+		}
+		fmt.Fprintf(sb, "%s DB %s\n", d.rotulos[uint8(addr)], lexer.Hex(d.Memory[addr]))
+		proximo = addr + 1
+	}
+}
+
+// referencia escreve o operando alvo pelo rótulo do endereço, se houver.
+func (d *Disassembler) referencia(alvo uint8) string {
+	if rotulo, ok := d.rotulos[alvo]; ok {
+		return rotulo
+	}
+	if rotulo, ok := d.rotulos[alvo-1]; ok && d.tipo[alvo] == operando {
+		return rotulo + "+1"
+	}
+	return lexer.Hex(alvo)
+}
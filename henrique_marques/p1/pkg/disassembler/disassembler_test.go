@@ -0,0 +1,169 @@
+package disassembler
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"p1/pkg/assembler"
+	"p1/pkg/compiler"
+	"p1/pkg/encoder"
+)
+
+// monta gera a imagem .mem do assembly.
+func monta(t *testing.T, nome, asm string) []byte {
+	t.Helper()
+	a, err := assembler.Assemble(asm, false, false)
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", nome, err, asm)
+	}
+	var imagem bytes.Buffer
+	if err := a.EscreveMEM(&imagem); err != nil {
+		t.Fatal(err)
+	}
+	return imagem.Bytes()
+}
+
+// imagem monta um .mem com o cabeçalho dado e as palavras a partir do
+// endereço 0.
+func imagem(cabecalho string, palavras ...uint8) []byte {
+	img := make([]byte, encoder.TOTAL_SIZE)
+	copy(img, "\x03"+cabecalho)
+	for i, p := range palavras {
+		img[encoder.HEADER_SIZE+i*2] = p
+	}
+	return img
+}
+
+// confereIdaEVolta desmonta a imagem, monta o resultado e exige a mesma
+// imagem byte a byte.
+func confereIdaEVolta(t *testing.T, nome string, original []byte) {
+	t.Helper()
+	d, err := NewDisassembler(original)
+	if err != nil {
+		t.Fatalf("%s: %v", nome, err)
+	}
+	asm := d.Disassemble()
+	remontada := monta(t, nome+" (desmontado)", asm)
+	if !bytes.Equal(remontada, original) {
+		for i := range original {
+			if i >= len(remontada) || remontada[i] != original[i] {
+				t.Fatalf("%s: imagens diferem a partir do byte %d\n%s", nome, i, asm)
+			}
+		}
+		t.Fatalf("%s: imagem remontada tem %d bytes, esperado %d", nome, len(remontada), len(original))
+	}
+}
+
+// TestIdaEVoltaTestdata desmonta as imagens dos programas de exemplo e dos
+// programas do harness de testes, em assembly ou compilados.
+func TestIdaEVoltaTestdata(t *testing.T) {
+	imagens, err := filepath.Glob("../../io/build/*.mem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, caminho := range imagens {
+		original, err := os.ReadFile(caminho)
+		if err != nil {
+			t.Fatal(err)
+		}
+		confereIdaEVolta(t, caminho, original)
+	}
+
+	fontes, err := filepath.Glob("../golden/testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asm, _ := filepath.Glob("../../io/asm/*.asm")
+	fontes = append(fontes, asm...)
+	for _, caminho := range fontes {
+		fonte, err := os.ReadFile(caminho)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch filepath.Ext(caminho) {
+		case ".asm":
+			confereIdaEVolta(t, caminho, monta(t, caminho, string(fonte)))
+		case ".ldh":
+			for _, otimiza := range []bool{false, true} {
+				gerado, err := compiler.Compile(string(fonte), otimiza)
+				if err != nil {
+					t.Fatalf("%s: %v", caminho, err)
+				}
+				confereIdaEVolta(t, caminho, monta(t, caminho, gerado))
+			}
+		}
+	}
+}
+
+func TestIdaEVolta(t *testing.T) {
+	casos := []struct {
+		nome   string
+		imagem []byte
+	}{
+		{nome: "vazia", imagem: imagem("NDR")},
+		{nome: "dados no meio do código", imagem: imagem("NDR",
+			encoder.JMP, 0x04,
+			0xDB, encoder.LDA, // nunca executados
+			encoder.LDA, 0x02,
+			encoder.HLT,
+		)},
+		{nome: "opcode desconhecido", imagem: imagem("NDR", 0x01, 0x7F, encoder.HLT)},
+		{nome: "desvio para o meio de uma instrução", imagem: imagem("NDR",
+			encoder.JZ, 0x01,
+			encoder.HLT,
+		)},
+		{nome: "código automodificável", imagem: imagem("NDR",
+			encoder.LDA, 0x03,
+			encoder.STA, 0x09,
+			encoder.HLT,
+		)},
+		{nome: "valor DB nos dados", imagem: imagem("NDR",
+			encoder.LDA, 0x04,
+			encoder.HLT,
+			0,
+			0xDB,
+		)},
+		{nome: "instruções do Ahmes", imagem: imagem("AHM",
+			encoder.LDA, 0x0C,
+			encoder.SUB, 0x0D,
+			encoder.JB, 0x08,
+			encoder.SHL,
+			encoder.ROR,
+			encoder.ROL,
+			encoder.SHR,
+			encoder.HLT,
+			0,
+			0x05, 0x07,
+		)},
+		{nome: "opcodes do Ahmes em imagem do Neander", imagem: imagem("NDR",
+			encoder.SUB, encoder.SHL, encoder.JC, encoder.HLT,
+		)},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			confereIdaEVolta(t, c.nome, c.imagem)
+		})
+	}
+}
+
+func TestDesmontagem(t *testing.T) {
+	d, err := NewDisassembler(imagem("AHM", encoder.LDA, 0x04, encoder.SHL, encoder.HLT, 0xDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asm := d.Disassemble()
+	for _, trecho := range []string{".AHMES", "LDA D_04", "SHL", "HLT", "D_04 DB 0DB"} {
+		if !strings.Contains(asm, trecho) {
+			t.Errorf("desmontagem sem %q:\n%s", trecho, asm)
+		}
+	}
+}
+
+func TestImagemCurta(t *testing.T) {
+	if _, err := NewDisassembler([]byte{0x03}); err == nil {
+		t.Error("esperado erro para imagem curta")
+	}
+}
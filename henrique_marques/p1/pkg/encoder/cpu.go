@@ -0,0 +1,362 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	MEM_SIZE    = 256
+	HEADER_SIZE = 4
+)
+
+var Mnemonicos = map[uint8]string{
+	NOP: "NOP", STA: "STA", LDA: "LDA", ADD: "ADD",
+	OR: "OR", AND: "AND", NOT: "NOT", JMP: "JMP",
+	JN: "JN", JZ: "JZ", HLT: "HLT",
+}
+
+var AhmesMnemonicos = map[uint8]string{
+	SUB: "SUB", JP: "JP", JV: "JV", JNV: "JNV",
+	JNZ: "JNZ", JC: "JC", JNC: "JNC", JB: "JB",
+	JNB: "JNB", SHR: "SHR", SHL: "SHL", ROR: "ROR",
+	ROL: "ROL",
+}
+
+var (
+	headerNeander = []byte{0x03, 0x4E, 0x44, 0x52} // "NDR"
+	headerAhmes   = []byte{0x03, 0x41, 0x48, 0x4D} // "AHM"
+)
+
+// ErrHalted é retornado por Step quando a CPU já executou um HLT.
+var ErrHalted = errors.New("cpu parada em HLT")
+
+// ImageError indica um arquivo .mem que não pode ser carregado.
+type ImageError struct {
+	Size int
+}
+
+func (e *ImageError) Error() string {
+	return fmt.Sprintf("arquivo .mem muito curto: %d bytes (mínimo %d)", e.Size, HEADER_SIZE)
+}
+
+// LimitError indica que Run executou o número máximo de instruções sem
+// encontrar um HLT.
+type LimitError struct {
+	Steps int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limite de %d instruções atingido sem HLT", e.Steps)
+}
+
+// CPU guarda o estado do Neander: acumulador, contador de programa,
+// flags N/Z, as 256 palavras de memória e contadores de execução. Com Ahmes
+// ligado, executa também as instruções do Ahmes e mantém as flags V, C e B.
+type CPU struct {
+	AC     uint8
+	PC     uint8
+	N      bool
+	Z      bool
+	V      bool
+	C      bool
+	B      bool
+	Memory [MEM_SIZE]uint8
+	Halted bool
+	Ahmes  bool
+
+	// Instructions conta as instruções executadas. Reads e Writes contam
+	// os acessos à memória: busca do opcode e do operando e leitura de
+	// dados são leituras; STA é a única escrita. No Neander cada acesso é
+	// um ciclo de memória, então Accesses() é o total de ciclos (veja a
+	// tabela em Step).
+	Instructions uint64
+	Reads        uint64
+	Writes       uint64
+
+	// IO, se não for nil, é o dispositivo ligado aos endereços IO_IN e
+	// IO_OUT.
+	IO Device
+
+	// transferencias conta os acessos ao dispositivo, para que RunWith
+	// saiba que a execução deixou de depender só do estado da máquina.
+	transferencias uint64
+	erroES         error
+
+	inicial [MEM_SIZE]uint8
+}
+
+func NewCPU() *CPU {
+	c := &CPU{}
+	c.atualizaFlags()
+	return c
+}
+
+// Load copia uma imagem .mem (cabeçalho de 4 bytes seguido de palavras de
+// 2 bytes) para a memória da CPU e a reinicia. O modo Ahmes passa a seguir
+// o cabeçalho da imagem: ligado com "AHM", desligado com qualquer outro.
+func (c *CPU) Load(imagem []byte) error {
+	if len(imagem) < HEADER_SIZE {
+		return &ImageError{Size: len(imagem)}
+	}
+	c.Ahmes = string(imagem[:HEADER_SIZE]) == string(headerAhmes)
+	c.inicial = [MEM_SIZE]uint8{}
+	for i := 0; i < MEM_SIZE; i++ {
+		pos := HEADER_SIZE + i*2
+		if pos >= len(imagem) {
+			break
+		}
+		c.inicial[i] = imagem[pos]
+	}
+	c.Reset()
+	return nil
+}
+
+// Reset zera registradores, flags e contadores e restaura a memória para a
+// última imagem carregada.
+func (c *CPU) Reset() {
+	c.Memory = c.inicial
+	c.AC = 0
+	c.PC = 0
+	c.Halted = false
+	c.V, c.C, c.B = false, false, false
+	c.Instructions = 0
+	c.Reads = 0
+	c.Writes = 0
+	c.atualizaFlags()
+}
+
+// Run executa até HLT. Se maxSteps for maior que zero e esse número de
+// instruções for executado sem HLT, retorna um *LimitError.
+func (c *CPU) Run(maxSteps int) error {
+	return c.RunWith(RunOptions{MaxSteps: maxSteps})
+}
+
+// Accesses devolve o total de acessos à memória (ciclos de memória).
+func (c *CPU) Accesses() uint64 {
+	return c.Reads + c.Writes
+}
+
+// Image devolve a memória no mesmo formato do arquivo .mem.
+func (c *CPU) Image() []byte {
+	imagem := make([]byte, TOTAL_SIZE)
+	if c.Ahmes {
+		copy(imagem, headerAhmes)
+	} else {
+		copy(imagem, headerNeander)
+	}
+	for i := 0; i < MEM_SIZE; i++ {
+		imagem[HEADER_SIZE+i*2] = c.Memory[i]
+	}
+	return imagem
+}
+
+// Mnemonico retorna o nome da instrução com o opcode op.
+func Mnemonico(op uint8, ahmes bool) (string, bool) {
+	if nome, ok := Mnemonicos[op]; ok {
+		return nome, true
+	}
+	if nome, ok := AhmesMnemonicos[op]; ok && ahmes {
+		return nome, true
+	}
+	return "", false
+}
+
+// InstructionSize retorna quantas palavras a instrução ocupa na memória.
+func InstructionSize(op uint8, ahmes bool) uint8 {
+	switch op {
+	case STA, LDA, ADD, OR, AND, JMP, JN, JZ:
+		return 2
+	case SUB, JP, JV, JNV, JNZ, JC, JNC, JB, JNB:
+		if ahmes {
+			return 2
+		}
+	}
+	return 1
+}
+
+func (c *CPU) atualizaFlags() {
+	c.Z = c.AC == 0x00
+	c.N = c.AC&0x80 != 0
+}
+
+func (c *CPU) operando() uint8 {
+	c.Reads++
+	return c.Memory[c.PC+1]
+}
+
+func (c *CPU) le(addr uint8) uint8 {
+	c.Reads++
+	if addr == IO_IN && c.IO != nil {
+		c.transferencias++
+		valor, err := c.IO.ReadByte()
+		if err != nil {
+			c.erroES = &IOError{PC: c.PC, Err: err}
+			return 0
+		}
+		c.Memory[addr] = valor
+	}
+	return c.Memory[addr]
+}
+
+func (c *CPU) escreve(addr uint8, valor uint8) {
+	c.Writes++
+	c.Memory[addr] = valor
+	if addr == IO_OUT && c.IO != nil {
+		c.transferencias++
+		if err := c.IO.WriteByte(valor); err != nil {
+			c.erroES = &IOError{PC: c.PC, Err: err}
+		}
+	}
+}
+
+// falhaES devolve, e esquece, o erro do dispositivo na última instrução.
+func (c *CPU) falhaES() error {
+	err := c.erroES
+	c.erroES = nil
+	return err
+}
+
+// Step executa uma única instrução. Ao encontrar HLT a CPU para e o PC
+// continua apontando para a instrução HLT. Uma falha do dispositivo de E/S
+// é devolvida como *IOError, depois de a instrução ser executada.
+//
+// Os acessos à memória seguem a tabela do Neander:
+//
+//	NOP, NOT, HLT (e SHR, SHL, ROR, ROL)   1 (opcode)
+//	JMP                                    2 (opcode, operando)
+//	JN, JZ (e desvios do Ahmes)            2 se desvia, 1 se não desvia
+//	LDA, ADD, OR, AND (e SUB)              3 (opcode, operando, leitura)
+//	STA                                    3 (opcode, operando, escrita)
+func (c *CPU) Step() error {
+	if c.Halted {
+		return ErrHalted
+	}
+
+	op := c.Memory[c.PC]
+	c.Reads++
+	c.Instructions++
+
+	if c.Ahmes && c.stepAhmes(op) {
+		return c.falhaES()
+	}
+
+	switch op {
+	case STA:
+		c.escreve(c.operando(), c.AC)
+		c.PC += 2
+	case LDA:
+		c.AC = c.le(c.operando())
+		c.atualizaFlags()
+		c.PC += 2
+	case ADD:
+		m := c.le(c.operando())
+		soma := c.AC + m
+		c.C = uint16(c.AC)+uint16(m) > 0xFF
+		c.V = (c.AC^soma)&(m^soma)&0x80 != 0
+		c.AC = soma
+		c.atualizaFlags()
+		c.PC += 2
+	case OR:
+		c.AC |= c.le(c.operando())
+		c.atualizaFlags()
+		c.PC += 2
+	case AND:
+		c.AC &= c.le(c.operando())
+		c.atualizaFlags()
+		c.PC += 2
+	case NOT:
+		c.AC = ^c.AC
+		c.atualizaFlags()
+		c.PC++
+	case JMP:
+		c.PC = c.operando()
+	case JN:
+		if c.N {
+			c.PC = c.operando()
+		} else {
+			c.PC += 2
+		}
+	case JZ:
+		if c.Z {
+			c.PC = c.operando()
+		} else {
+			c.PC += 2
+		}
+	case HLT:
+		c.Halted = true
+	default:
+		c.PC++
+	}
+	return c.falhaES()
+}
+
+// stepAhmes executa as instruções exclusivas do Ahmes. Retorna falso se op
+// não for uma delas.
+func (c *CPU) stepAhmes(op uint8) bool {
+	desvia := func(condicao bool) {
+		if condicao {
+			c.PC = c.operando()
+		} else {
+			c.PC += 2
+		}
+	}
+
+	switch op {
+	case SUB:
+		m := c.le(c.operando())
+		dif := c.AC - m
+		c.B = c.AC < m
+		c.V = (c.AC^m)&(c.AC^dif)&0x80 != 0
+		c.AC = dif
+		c.atualizaFlags()
+		c.PC += 2
+	case JP:
+		desvia(!c.N && !c.Z)
+	case JV:
+		desvia(c.V)
+	case JNV:
+		desvia(!c.V)
+	case JNZ:
+		desvia(!c.Z)
+	case JC:
+		desvia(c.C)
+	case JNC:
+		desvia(!c.C)
+	case JB:
+		desvia(c.B)
+	case JNB:
+		desvia(!c.B)
+	case SHR:
+		c.C = c.AC&0x01 != 0
+		c.AC >>= 1
+		c.atualizaFlags()
+		c.PC++
+	case SHL:
+		c.C = c.AC&0x80 != 0
+		c.AC <<= 1
+		c.atualizaFlags()
+		c.PC++
+	case ROR:
+		carry := c.C
+		c.C = c.AC&0x01 != 0
+		c.AC >>= 1
+		if carry {
+			c.AC |= 0x80
+		}
+		c.atualizaFlags()
+		c.PC++
+	case ROL:
+		carry := c.C
+		c.C = c.AC&0x80 != 0
+		c.AC <<= 1
+		if carry {
+			c.AC |= 0x01
+		}
+		c.atualizaFlags()
+		c.PC++
+	default:
+		return false
+	}
+	return true
+}
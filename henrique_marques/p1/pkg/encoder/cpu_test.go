@@ -0,0 +1,267 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+)
+
+// imagem monta um .mem com o cabeçalho dado e as palavras a partir do
+// endereço 0.
+func imagem(cabecalho []byte, palavras ...uint8) []byte {
+	img := make([]byte, TOTAL_SIZE)
+	copy(img, cabecalho)
+	for i, p := range palavras {
+		img[HEADER_SIZE+i*2] = p
+	}
+	return img
+}
+
+func TestLoad(t *testing.T) {
+	casos := []struct {
+		nome   string
+		imagem []byte
+		ahmes  bool
+		erro   bool
+	}{
+		{nome: "neander", imagem: imagem(headerNeander, LDA, 0x80, HLT)},
+		{nome: "ahmes", imagem: imagem(headerAhmes, SHL, HLT), ahmes: true},
+		{nome: "cabeçalho desconhecido", imagem: imagem([]byte{0, 0, 0, 0}, HLT)},
+		{nome: "imagem curta", imagem: headerAhmes[:2], erro: true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			// A CPU vem de uma imagem do Ahmes: o modo não pode vazar
+			// para a próxima imagem.
+			cpu := NewCPU()
+			if err := cpu.Load(imagem(headerAhmes, ROL)); err != nil {
+				t.Fatal(err)
+			}
+			err := cpu.Load(c.imagem)
+			var erroImagem *ImageError
+			if c.erro {
+				if !errors.As(err, &erroImagem) {
+					t.Fatalf("erro %v, esperado *ImageError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cpu.Ahmes != c.ahmes {
+				t.Errorf("Ahmes = %v, esperado %v", cpu.Ahmes, c.ahmes)
+			}
+			if cpu.Memory[0] != c.imagem[HEADER_SIZE] {
+				t.Errorf("memória[0] = %02X, esperado %02X", cpu.Memory[0], c.imagem[HEADER_SIZE])
+			}
+		})
+	}
+}
+
+// Uma imagem do Neander carregada depois de uma do Ahmes trata os opcodes
+// do Ahmes como NOP.
+func TestLoadDesligaAhmes(t *testing.T) {
+	cpu := NewCPU()
+	if err := cpu.Load(imagem(headerAhmes, HLT)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cpu.Load(imagem(headerNeander, LDA, 0x10, SHL, HLT)); err != nil {
+		t.Fatal(err)
+	}
+	cpu.Memory[0x10] = 0x01
+	if err := cpu.Run(10); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.AC != 0x01 {
+		t.Errorf("AC = %02X: SHL foi executado em uma imagem do Neander", cpu.AC)
+	}
+}
+
+// Cada caso executa uma instrução a partir do estado dado; X, no endereço
+// 10, é o operando das instruções de memória.
+func TestStep(t *testing.T) {
+	casos := []struct {
+		nome           string
+		ac             uint8
+		n, z           bool
+		palavras       []uint8
+		x              uint8
+		acFinal        uint8
+		pc             uint8
+		nFinal, zFinal bool
+		xFinal         uint8
+		leituras       uint64
+		escritas       uint64
+	}{
+		{nome: "NOP", ac: 0x01, palavras: []uint8{NOP}, acFinal: 0x01, pc: 0x01, leituras: 1},
+		{nome: "LDA", palavras: []uint8{LDA, 0x10}, x: 0x80, acFinal: 0x80, pc: 0x02, nFinal: true, xFinal: 0x80, leituras: 3},
+		{nome: "LDA zero", ac: 0x05, palavras: []uint8{LDA, 0x10}, acFinal: 0x00, pc: 0x02, zFinal: true, leituras: 3},
+		{nome: "STA", ac: 0x2A, palavras: []uint8{STA, 0x10}, acFinal: 0x2A, pc: 0x02, xFinal: 0x2A, leituras: 2, escritas: 1},
+		{nome: "ADD", ac: 0x09, palavras: []uint8{ADD, 0x10}, x: 0xFF, acFinal: 0x08, pc: 0x02, xFinal: 0xFF, leituras: 3},
+		{nome: "ADD com resultado zero", ac: 0x01, palavras: []uint8{ADD, 0x10}, x: 0xFF, acFinal: 0x00, pc: 0x02, zFinal: true, xFinal: 0xFF, leituras: 3},
+		{nome: "OR", ac: 0x0F, palavras: []uint8{OR, 0x10}, x: 0xF0, acFinal: 0xFF, pc: 0x02, nFinal: true, xFinal: 0xF0, leituras: 3},
+		{nome: "AND", ac: 0x0F, palavras: []uint8{AND, 0x10}, x: 0xF0, acFinal: 0x00, pc: 0x02, zFinal: true, xFinal: 0xF0, leituras: 3},
+		{nome: "NOT", ac: 0x0F, palavras: []uint8{NOT}, acFinal: 0xF0, pc: 0x01, nFinal: true, leituras: 1},
+		{nome: "JMP", palavras: []uint8{JMP, 0x20}, pc: 0x20, leituras: 2},
+		{nome: "JN desvia", ac: 0x80, n: true, palavras: []uint8{JN, 0x20}, acFinal: 0x80, pc: 0x20, nFinal: true, leituras: 2},
+		{nome: "JN não desvia", ac: 0x01, palavras: []uint8{JN, 0x20}, acFinal: 0x01, pc: 0x02, leituras: 1},
+		{nome: "JZ desvia", z: true, palavras: []uint8{JZ, 0x20}, pc: 0x20, zFinal: true, leituras: 2},
+		{nome: "JZ não desvia", ac: 0x01, palavras: []uint8{JZ, 0x20}, acFinal: 0x01, pc: 0x02, leituras: 1},
+		{nome: "opcode desconhecido", ac: 0x01, palavras: []uint8{0x01}, acFinal: 0x01, pc: 0x01, leituras: 1},
+		{nome: "opcode do Ahmes no Neander", ac: 0x01, palavras: []uint8{SHL}, acFinal: 0x01, pc: 0x01, leituras: 1},
+		{nome: "HLT", ac: 0x01, palavras: []uint8{HLT}, acFinal: 0x01, pc: 0x00, leituras: 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			cpu := NewCPU()
+			if err := cpu.Load(imagem(headerNeander, c.palavras...)); err != nil {
+				t.Fatal(err)
+			}
+			cpu.AC, cpu.N, cpu.Z = c.ac, c.n, c.z
+			cpu.Memory[0x10] = c.x
+			if err := cpu.Step(); err != nil {
+				t.Fatal(err)
+			}
+			if cpu.AC != c.acFinal || cpu.PC != c.pc || cpu.N != c.nFinal || cpu.Z != c.zFinal {
+				t.Errorf("AC=%02X PC=%02X N=%v Z=%v, esperado AC=%02X PC=%02X N=%v Z=%v",
+					cpu.AC, cpu.PC, cpu.N, cpu.Z, c.acFinal, c.pc, c.nFinal, c.zFinal)
+			}
+			if cpu.Memory[0x10] != c.xFinal {
+				t.Errorf("X = %02X, esperado %02X", cpu.Memory[0x10], c.xFinal)
+			}
+			if cpu.Instructions != 1 || cpu.Reads != c.leituras || cpu.Writes != c.escritas {
+				t.Errorf("instruções=%d leituras=%d escritas=%d, esperado 1, %d e %d",
+					cpu.Instructions, cpu.Reads, cpu.Writes, c.leituras, c.escritas)
+			}
+			if cpu.Halted != (c.palavras[0] == HLT) {
+				t.Errorf("Halted = %v", cpu.Halted)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	// X = 3 + 4.
+	soma := imagem(headerNeander,
+		LDA, 0x10,
+		ADD, 0x11,
+		STA, 0x12,
+		HLT,
+	)
+	soma[HEADER_SIZE+0x10*2] = 3
+	soma[HEADER_SIZE+0x11*2] = 4
+
+	cpu := NewCPU()
+	if err := cpu.Load(soma); err != nil {
+		t.Fatal(err)
+	}
+	if err := cpu.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Memory[0x12] != 7 || !cpu.Halted || cpu.PC != 0x06 {
+		t.Fatalf("X=%d Halted=%v PC=%02X", cpu.Memory[0x12], cpu.Halted, cpu.PC)
+	}
+	if cpu.Instructions != 4 || cpu.Accesses() != 10 {
+		t.Errorf("instruções=%d acessos=%d, esperado 4 e 10", cpu.Instructions, cpu.Accesses())
+	}
+	if err := cpu.Step(); !errors.Is(err, ErrHalted) {
+		t.Errorf("Step depois de HLT: %v, esperado ErrHalted", err)
+	}
+
+	// Image devolve a memória final no formato do .mem.
+	if img := cpu.Image(); len(img) != TOTAL_SIZE || img[HEADER_SIZE+0x12*2] != 7 || string(img[:HEADER_SIZE]) != string(headerNeander) {
+		t.Errorf("Image: % X", img[:HEADER_SIZE])
+	}
+
+	// Reset volta à imagem carregada e zera registradores e contadores.
+	cpu.Reset()
+	if cpu.Memory[0x12] != 0 || cpu.PC != 0 || cpu.AC != 0 || cpu.Halted || cpu.Instructions != 0 || cpu.Accesses() != 0 || !cpu.Z {
+		t.Errorf("estado depois de Reset: %+v", cpu)
+	}
+}
+
+func TestRunLimite(t *testing.T) {
+	cpu := NewCPU()
+	if err := cpu.Load(imagem(headerNeander, JMP, 0x00)); err != nil {
+		t.Fatal(err)
+	}
+	err := cpu.Run(50)
+	var limite *LimitError
+	if !errors.As(err, &limite) || limite.Steps != 50 || cpu.Instructions != 50 {
+		t.Fatalf("erro %v depois de %d instruções, esperado *LimitError em 50", err, cpu.Instructions)
+	}
+	if err.Error() != "limite de 50 instruções atingido sem HLT" {
+		t.Errorf("mensagem: %s", err)
+	}
+}
+
+// Cada caso executa uma instrução do Ahmes; X, no endereço 10, é o
+// operando de SUB e ADD. Os desvios vão para 20.
+func TestStepAhmes(t *testing.T) {
+	type flags struct{ n, z, v, c, b bool }
+	casos := []struct {
+		nome     string
+		ac       uint8
+		antes    flags
+		palavras []uint8
+		x        uint8
+		acFinal  uint8
+		pc       uint8
+		depois   flags
+	}{
+		{nome: "SUB", ac: 0x07, palavras: []uint8{SUB, 0x10}, x: 0x05, acFinal: 0x02, pc: 0x02},
+		{nome: "SUB com empréstimo", ac: 0x05, palavras: []uint8{SUB, 0x10}, x: 0x07, acFinal: 0xFE, pc: 0x02, depois: flags{n: true, b: true}},
+		{nome: "SUB com estouro", ac: 0x80, palavras: []uint8{SUB, 0x10}, x: 0x01, acFinal: 0x7F, pc: 0x02, depois: flags{v: true}},
+		{nome: "ADD com vai-um", ac: 0xFF, palavras: []uint8{ADD, 0x10}, x: 0x01, acFinal: 0x00, pc: 0x02, depois: flags{z: true, c: true}},
+		{nome: "ADD com estouro", ac: 0x7F, palavras: []uint8{ADD, 0x10}, x: 0x01, acFinal: 0x80, pc: 0x02, depois: flags{n: true, v: true}},
+		{nome: "JP desvia", ac: 0x01, palavras: []uint8{JP, 0x20}, acFinal: 0x01, pc: 0x20},
+		{nome: "JP não desvia no zero", antes: flags{z: true}, palavras: []uint8{JP, 0x20}, pc: 0x02, depois: flags{z: true}},
+		{nome: "JV", antes: flags{v: true}, palavras: []uint8{JV, 0x20}, pc: 0x20, depois: flags{v: true}},
+		{nome: "JNV", palavras: []uint8{JNV, 0x20}, pc: 0x20},
+		{nome: "JNZ", palavras: []uint8{JNZ, 0x20}, pc: 0x20},
+		{nome: "JC", antes: flags{c: true}, palavras: []uint8{JC, 0x20}, pc: 0x20, depois: flags{c: true}},
+		{nome: "JNC não desvia", antes: flags{c: true}, palavras: []uint8{JNC, 0x20}, pc: 0x02, depois: flags{c: true}},
+		{nome: "JB", antes: flags{b: true}, palavras: []uint8{JB, 0x20}, pc: 0x20, depois: flags{b: true}},
+		{nome: "JNB", palavras: []uint8{JNB, 0x20}, pc: 0x20},
+		{nome: "SHR", ac: 0x81, palavras: []uint8{SHR}, acFinal: 0x40, pc: 0x01, depois: flags{c: true}},
+		{nome: "SHL", ac: 0x81, palavras: []uint8{SHL}, acFinal: 0x02, pc: 0x01, depois: flags{c: true}},
+		{nome: "ROR", ac: 0x02, antes: flags{c: true}, palavras: []uint8{ROR}, acFinal: 0x81, pc: 0x01, depois: flags{n: true}},
+		{nome: "ROL", ac: 0x80, antes: flags{c: true}, palavras: []uint8{ROL}, acFinal: 0x01, pc: 0x01, depois: flags{c: true}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			cpu := NewCPU()
+			if err := cpu.Load(imagem(headerAhmes, c.palavras...)); err != nil {
+				t.Fatal(err)
+			}
+			cpu.AC = c.ac
+			cpu.N, cpu.Z, cpu.V, cpu.C, cpu.B = c.antes.n, c.antes.z, c.antes.v, c.antes.c, c.antes.b
+			cpu.Memory[0x10] = c.x
+			if err := cpu.Step(); err != nil {
+				t.Fatal(err)
+			}
+			obtidas := flags{cpu.N, cpu.Z, cpu.V, cpu.C, cpu.B}
+			if cpu.AC != c.acFinal || cpu.PC != c.pc || obtidas != c.depois {
+				t.Errorf("AC=%02X PC=%02X %+v, esperado AC=%02X PC=%02X %+v", cpu.AC, cpu.PC, obtidas, c.acFinal, c.pc, c.depois)
+			}
+		})
+	}
+}
+
+func TestInstructionSize(t *testing.T) {
+	casos := []struct {
+		op      uint8
+		ahmes   bool
+		tamanho uint8
+	}{
+		{LDA, false, 2}, {JZ, false, 2}, {NOT, false, 1}, {HLT, false, 1},
+		{SUB, false, 1}, {SUB, true, 2}, {JNB, true, 2}, {SHL, true, 1}, {0x01, true, 1},
+	}
+	for _, c := range casos {
+		if tamanho := InstructionSize(c.op, c.ahmes); tamanho != c.tamanho {
+			t.Errorf("InstructionSize(%02X, %v) = %d, esperado %d", c.op, c.ahmes, tamanho, c.tamanho)
+		}
+		if _, ok := Mnemonico(c.op, c.ahmes); ok != (c.op != 0x01 && (c.ahmes || c.op != SUB)) {
+			t.Errorf("Mnemonico(%02X, %v): ok = %v", c.op, c.ahmes, ok)
+		}
+	}
+}
@@ -1,106 +1,117 @@
-package encoder
-
-import (
-	"fmt"
-	"log"
-	"os"
-)
-
-const (
-	TOTAL_SIZE = 516
-
-	NOP = 0x00
-	STA = 0x10
-	LDA = 0x20
-	ADD = 0x30
-	OR  = 0x40
-	AND = 0x50
-	NOT = 0x60
-	JMP = 0x80
-	JN  = 0x90
-	JZ  = 0xA0
-	HLT = 0xF0
-)
-
-func flagZero(AC int) bool {
-	return AC == 0x00
-}
-
-func flagNeg(AC int) bool {
-	return AC & 0x80 != 0
-}
-
-func RunBinary(caminhoArquivo string) {
-	AC := 0
-	PC := 0x04
-
-	memory, err := os.ReadFile(caminhoArquivo)
-	if err != nil {
-		log.Fatalf("Não foi possível ler o arquivo!")
-		return
-	}
-
-	posicao := 0
-
-	for memory[PC] != HLT && PC <= 0xFF {
-		fmt.Printf("AC: %2x PC: %2x FZ: %5t FN: %5t INSTRUCAO: %2x CONTEUDO: %2x\n", AC & 0xFF, PC, flagZero(AC), flagNeg(AC), memory[PC], memory[PC+2])
-
-		switch memory[PC] {
-			case STA:
-				PC += 2
-				posicao = int(memory[PC]) * 2 + 4
-				memory[posicao] = byte(AC)
-				PC += 2
-			case LDA:
-				PC += 2
-				posicao = int(memory[PC]) * 2 + 4
-				AC = int(memory[posicao])
-				PC += 2
-			case ADD:
-				PC += 2
-				posicao = int(memory[PC]) * 2 + 4
-				AC += int(memory[posicao])
-				PC += 2
-			case OR:
-				PC += 2
-				posicao = int(memory[PC]) * 2 + 4
-				AC |= int(memory[posicao])
-				PC += 2
-			case AND:
-				PC += 2
-				posicao = int(memory[PC]) * 2 + 4
-				AC &= int(memory[posicao])
-				PC += 2
-			case NOT:
-				AC = ^AC
-				PC += 2
-			case JMP:
-				PC += 2
-				PC = int(memory[PC]) * 2 + 4
-			case JN:
-				PC += 2
-				if flagNeg(AC) {
-					PC = int(memory[PC]) * 2 + 4
-				} else {
-					PC += 2
-				}
-			case JZ:
-				PC += 2
-				if flagZero(AC) {
-					PC = int(memory[PC]) * 2 + 4
-				} else {
-					PC += 2
-				}
-			default:
-				PC += 2
-		}
-	}
-
-	fmt.Println("========== Retorno de Memória ===========")
-	for i := 0; i < TOTAL_SIZE; i++ {
-		fmt.Printf("%3x:%3x ", i, memory[i])
-		if i%16 == 15 {
-			fmt.Println()
-		}
-	}
-}
\ No newline at end of file
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"p1/pkg/simbolos"
+)
+
+const (
+	TOTAL_SIZE = 516
+
+	NOP = 0x00
+	STA = 0x10
+	LDA = 0x20
+	ADD = 0x30
+	OR  = 0x40
+	AND = 0x50
+	NOT = 0x60
+	JMP = 0x80
+	JN  = 0x90
+	JZ  = 0xA0
+	HLT = 0xF0
+
+	// Instruções adicionais do Ahmes.
+	SUB = 0x70
+	JP  = 0x94
+	JV  = 0x98
+	JNV = 0x9C
+	JNZ = 0xA4
+	JC  = 0xB0
+	JNC = 0xB4
+	JB  = 0xB8
+	JNB = 0xBC
+	SHR = 0xE0
+	SHL = 0xE1
+	ROR = 0xE2
+	ROL = 0xE3
+)
+
+// RunBinary executa o arquivo .mem e imprime o rastro, o relatório da
+// execução e a memória final. O modo Ahmes é ligado pelo cabeçalho do
+// arquivo ou forçado por ahmes. Com uma tabela de símbolos, a memória final
+// é mostrada por variável. A execução é limitada por opcoes (veja
+// CPU.RunWith); o rastro de opcoes é ignorado.
+func RunBinary(caminhoArquivo string, ahmes bool, tabela *simbolos.Tabela, opcoes RunOptions) error {
+	imagem, err := os.ReadFile(caminhoArquivo)
+	if err != nil {
+		return fmt.Errorf("não foi possível ler o arquivo: %w", err)
+	}
+	return RunImage(imagem, ahmes, tabela, opcoes, os.Stdout)
+}
+
+// RunImage executa uma imagem .mem já lida, escrevendo em out o rastro, o
+// relatório e a memória final, como RunBinary. Se a execução parar por um
+// limite, o relatório e a memória são escritos mesmo assim e o erro do
+// limite é devolvido.
+func RunImage(imagem []byte, ahmes bool, tabela *simbolos.Tabela, opcoes RunOptions, out io.Writer) error {
+	cpu := NewCPU()
+	if err := cpu.Load(imagem); err != nil {
+		return fmt.Errorf("não foi possível carregar o arquivo: %w", err)
+	}
+	if ahmes {
+		cpu.Ahmes = true
+	}
+
+	opcoes.Trace = out
+	inicio := time.Now()
+	errExec := cpu.RunWith(opcoes)
+	escreveRelatorio(out, cpu, time.Since(inicio), errExec)
+
+	if tabela != nil {
+		escreveVariaveis(out, cpu, tabela)
+		return errExec
+	}
+
+	memory := cpu.Image()
+
+	fmt.Fprintln(out, "========== Retorno de Memória ===========")
+	for i := 0; i < TOTAL_SIZE; i++ {
+		fmt.Fprintf(out, "%3x:%3x ", i, memory[i])
+		if i%16 == 15 {
+			fmt.Fprintln(out)
+		}
+	}
+	return errExec
+}
+
+// escreveRelatorio resume a execução: como ela terminou, as instruções
+// executadas e os acessos à memória.
+func escreveRelatorio(out io.Writer, cpu *CPU, duracao time.Duration, errExec error) {
+	fmt.Fprintln(out, "========== Execução ===========")
+	if errExec != nil {
+		fmt.Fprintf(out, "Interrompida: %v\n", errExec)
+	} else {
+		fmt.Fprintf(out, "Parada em HLT no endereço %02X\n", cpu.PC)
+	}
+	fmt.Fprintf(out, "Instruções executadas: %d\n", cpu.Instructions)
+	fmt.Fprintf(out, "Acessos à memória: %d (leituras: %d, escritas: %d)\n", cpu.Accesses(), cpu.Reads, cpu.Writes)
+	fmt.Fprintf(out, "Tempo: %v\n", duracao.Round(time.Microsecond))
+}
+
+// escreveVariaveis mostra o valor final de cada símbolo de dados da tabela,
+// em hexadecimal e em decimal, com a descrição dos temporários.
+func escreveVariaveis(out io.Writer, cpu *CPU, tabela *simbolos.Tabela) {
+	fmt.Fprintln(out, "========== Variáveis ===========")
+	for _, s := range tabela.Dados() {
+		valor := cpu.Memory[s.Endereco]
+		fmt.Fprintf(out, "%-16s [%02X] = %02X (%d)", s.Nome, s.Endereco, valor, valor)
+		if s.Descricao != "" {
+			fmt.Fprintf(out, "  ; %s", s.Descricao)
+		}
+		fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,70 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBinaryArquivoInexistente(t *testing.T) {
+	err := RunBinary(filepath.Join(t.TempDir(), "nada.mem"), false, nil, RunOptions{})
+	if !errors.Is(err, os.ErrNotExist) || !strings.HasPrefix(err.Error(), "não foi possível ler o arquivo") {
+		t.Errorf("erro %v, esperado arquivo inexistente", err)
+	}
+}
+
+func TestRunImage(t *testing.T) {
+	casos := []struct {
+		nome      string
+		imagem    []byte
+		opcoes    RunOptions
+		erro      string
+		relatorio []string
+	}{
+		{nome: "imagem curta", imagem: headerNeander[:3], erro: "não foi possível carregar o arquivo"},
+		{
+			nome:      "HLT",
+			imagem:    imagem(headerNeander, LDA, 0x03, HLT, 0x2A),
+			relatorio: []string{"Parada em HLT no endereço 02", "Instruções executadas: 2", "Acessos à memória: 4 (leituras: 4, escritas: 0)", "Retorno de Memória"},
+		},
+		{
+			nome:      "limite",
+			imagem:    imagem(headerNeander, JMP, 0x00),
+			opcoes:    RunOptions{MaxSteps: 5},
+			erro:      "limite de 5 instruções atingido sem HLT",
+			relatorio: []string{"Interrompida: limite de 5 instruções", "Instruções executadas: 5", "Retorno de Memória"},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			var saida bytes.Buffer
+			err := RunImage(c.imagem, false, nil, c.opcoes, &saida)
+			switch {
+			case c.erro == "" && err != nil:
+				t.Fatalf("erro inesperado: %v", err)
+			case c.erro != "" && (err == nil || !strings.Contains(err.Error(), c.erro)):
+				t.Fatalf("erro %v, esperado %q", err, c.erro)
+			}
+			for _, trecho := range c.relatorio {
+				if !strings.Contains(saida.String(), trecho) {
+					t.Errorf("saída sem %q:\n%s", trecho, saida.String())
+				}
+			}
+		})
+	}
+}
+
+// Um erro do dispositivo também chega a quem chamou RunImage, depois do
+// relatório.
+func TestRunImageErroES(t *testing.T) {
+	var saida bytes.Buffer
+	err := RunImage(imagem(headerNeander, LDA, IO_IN, HLT), false, nil, RunOptions{Device: NewDevice(strings.NewReader(""), io.Discard)}, &saida)
+	var erroES *IOError
+	if !errors.As(err, &erroES) || !strings.Contains(saida.String(), "Interrompida: entrada esgotada") {
+		t.Errorf("erro %v, saída:\n%s", err, saida.String())
+	}
+}
@@ -0,0 +1,114 @@
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Endereços de entrada e saída mapeados em memória. Com um dispositivo
+// ligado à CPU, ler um dado de IO_IN (LDA, ADD, ...) lê um byte do
+// dispositivo e gravar em IO_OUT (STA) o envia ao dispositivo. Nos dois
+// casos o valor também fica na memória, como em uma posição comum.
+const (
+	IO_IN  = 0xFE
+	IO_OUT = 0xFF
+)
+
+// Device é um dispositivo de entrada e saída. bufio.Reader e bufio.Writer
+// já implementam cada metade.
+type Device interface {
+	ReadByte() (byte, error)
+	WriteByte(byte) error
+}
+
+// IOError indica uma falha do dispositivo ao executar a instrução em PC,
+// como a entrada acabar antes de um LEIA.
+type IOError struct {
+	PC  uint8
+	Err error
+}
+
+func (e *IOError) Error() string {
+	if e.Err == io.EOF {
+		return fmt.Sprintf("entrada esgotada na instrução em PC=%02X", e.PC)
+	}
+	return fmt.Sprintf("erro de E/S na instrução em PC=%02X: %v", e.PC, e.Err)
+}
+
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// NewDevice cria um dispositivo que lê e escreve bytes sem conversão.
+func NewDevice(r io.Reader, w io.Writer) Device {
+	return &dispositivo{r: bufio.NewReader(r), w: w}
+}
+
+type dispositivo struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func (d *dispositivo) ReadByte() (byte, error) {
+	return d.r.ReadByte()
+}
+
+func (d *dispositivo) WriteByte(b byte) error {
+	_, err := d.w.Write([]byte{b})
+	return err
+}
+
+// NewTextDevice cria um dispositivo que lê números decimais separados por
+// espaços ou quebras de linha (de -128 a 255; os negativos em complemento
+// de dois) e escreve cada byte como um número decimal em sua própria linha.
+func NewTextDevice(r io.Reader, w io.Writer) Device {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	return &dispositivoTexto{s: s, w: w}
+}
+
+type dispositivoTexto struct {
+	s *bufio.Scanner
+	w io.Writer
+}
+
+func (d *dispositivoTexto) ReadByte() (byte, error) {
+	if !d.s.Scan() {
+		if err := d.s.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	palavra := d.s.Text()
+	n, err := strconv.Atoi(strings.TrimPrefix(palavra, "+"))
+	if err != nil || n < -128 || n > 255 {
+		return 0, fmt.Errorf("número inválido na entrada: %q (esperado de -128 a 255)", palavra)
+	}
+	return byte(n), nil
+}
+
+func (d *dispositivoTexto) WriteByte(b byte) error {
+	_, err := fmt.Fprintln(d.w, b)
+	return err
+}
+
+// ModosES lista os modos aceitos por DeviceByName.
+const ModosES = "numeros, bytes ou nenhum"
+
+// DeviceByName cria o dispositivo do modo indicado: "numeros"
+// (NewTextDevice), "bytes" (NewDevice) ou "nenhum" (nil: os endereços de
+// E/S são posições comuns da memória).
+func DeviceByName(modo string, r io.Reader, w io.Writer) (Device, error) {
+	switch modo {
+	case "numeros":
+		return NewTextDevice(r, w), nil
+	case "bytes":
+		return NewDevice(r, w), nil
+	case "nenhum":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("modo de E/S desconhecido: %s (use %s)", modo, ModosES)
+}
@@ -0,0 +1,142 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDispositivoTexto(t *testing.T) {
+	casos := []struct {
+		entrada string
+		lidos   []byte
+		erro    string
+	}{
+		{entrada: "1 2\n255", lidos: []byte{1, 2, 255}},
+		{entrada: "-1 +7 -128", lidos: []byte{0xFF, 7, 0x80}},
+		{entrada: "", erro: io.EOF.Error()},
+		{entrada: "3 256", lidos: []byte{3}, erro: `número inválido na entrada: "256"`},
+		{entrada: "-129", erro: `número inválido na entrada: "-129"`},
+		{entrada: "0x10", erro: `número inválido na entrada: "0x10"`},
+	}
+	for _, c := range casos {
+		d := NewTextDevice(strings.NewReader(c.entrada), io.Discard)
+		var lidos []byte
+		var err error
+		for {
+			var b byte
+			if b, err = d.ReadByte(); err != nil {
+				break
+			}
+			lidos = append(lidos, b)
+		}
+		if !bytes.Equal(lidos, c.lidos) {
+			t.Errorf("%q: lidos %v, esperado %v", c.entrada, lidos, c.lidos)
+		}
+		if c.erro == "" {
+			c.erro = io.EOF.Error()
+		}
+		if !strings.Contains(err.Error(), c.erro) {
+			t.Errorf("%q: erro %v, esperado %q", c.entrada, err, c.erro)
+		}
+	}
+
+	var saida bytes.Buffer
+	d := NewTextDevice(strings.NewReader(""), &saida)
+	for _, b := range []byte{0, 200, 0xFF} {
+		if err := d.WriteByte(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if saida.String() != "0\n200\n255\n" {
+		t.Errorf("saída %q", saida.String())
+	}
+}
+
+func TestDeviceByName(t *testing.T) {
+	for _, modo := range []string{"numeros", "bytes"} {
+		if d, err := DeviceByName(modo, strings.NewReader(""), io.Discard); d == nil || err != nil {
+			t.Errorf("DeviceByName(%q) = %v, %v", modo, d, err)
+		}
+	}
+	if d, err := DeviceByName("nenhum", nil, nil); d != nil || err != nil {
+		t.Errorf("DeviceByName(\"nenhum\") = %v, %v", d, err)
+	}
+	_, err := DeviceByName("texto", nil, nil)
+	if err == nil || err.Error() != "modo de E/S desconhecido: texto (use "+ModosES+")" {
+		t.Errorf("erro %v", err)
+	}
+}
+
+func TestES(t *testing.T) {
+	// Lê dois números, escreve a soma e a guarda também em X (10).
+	soma := imagem(headerNeander,
+		LDA, IO_IN,
+		STA, 0x10,
+		LDA, IO_IN,
+		ADD, 0x10,
+		STA, IO_OUT,
+		HLT,
+	)
+	casos := []struct {
+		nome    string
+		entrada string
+		saida   string
+		erro    string
+		memoria uint8
+	}{
+		{nome: "soma", entrada: "7 5", saida: "12\n", memoria: 12},
+		{nome: "entrada esgotada", entrada: "7", erro: "entrada esgotada na instrução em PC=04"},
+		{nome: "entrada inválida", entrada: "7 x", erro: `erro de E/S na instrução em PC=04: número inválido na entrada: "x" (esperado de -128 a 255)`},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			var saida bytes.Buffer
+			cpu := carrega(t, soma)
+			err := cpu.RunWith(RunOptions{Device: NewTextDevice(strings.NewReader(c.entrada), &saida)})
+			if c.erro != "" {
+				var erroES *IOError
+				if !errors.As(err, &erroES) || err.Error() != c.erro {
+					t.Fatalf("erro %v, esperado %q", err, c.erro)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saida.String() != c.saida || cpu.Memory[IO_OUT] != c.memoria {
+				t.Errorf("saída %q, memória em FF %d", saida.String(), cpu.Memory[IO_OUT])
+			}
+		})
+	}
+
+	// Sem dispositivo, FE e FF são posições comuns da memória.
+	cpu := carrega(t, soma)
+	cpu.Memory[IO_IN] = 4
+	if err := cpu.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Memory[IO_OUT] != 8 {
+		t.Errorf("FF = %d, esperado 8", cpu.Memory[IO_OUT])
+	}
+}
+
+// Ler a entrada muda o estado por fora da máquina: um laço que lê sempre o
+// mesmo valor não é um laço infinito enquanto houver entrada.
+func TestRunWithEntradaRecomecaDeteccao(t *testing.T) {
+	cpu := carrega(t, imagem(headerNeander, LDA, IO_IN, JMP, 0x00))
+	var saida bytes.Buffer
+	err := cpu.RunWith(RunOptions{
+		DetectLoops: true,
+		Device:      NewDevice(bytes.NewReader(make([]byte, 50)), &saida),
+	})
+	var erroES *IOError
+	if !errors.As(err, &erroES) || erroES.PC != 0x00 {
+		t.Fatalf("erro %v, esperado *IOError em PC=00", err)
+	}
+	if cpu.Instructions != 101 {
+		t.Errorf("%d instruções, esperado 101 (50 voltas e a leitura que falhou)", cpu.Instructions)
+	}
+}
@@ -0,0 +1,126 @@
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// RunOptions limita uma execução por RunWith. Valores zero desligam cada
+// limite.
+type RunOptions struct {
+	// MaxSteps é o número máximo de instruções executadas.
+	MaxSteps int
+	// Timeout é o tempo máximo de execução.
+	Timeout time.Duration
+	// DetectLoops interrompe a execução quando a máquina volta a um
+	// estado (registradores, flags e memória) já visto: como o Neander é
+	// determinístico, a partir daí o programa repetiria o mesmo trecho
+	// para sempre. Cada acesso ao dispositivo de E/S recomeça a
+	// detecção, já que a entrada e a saída não fazem parte do estado.
+	DetectLoops bool
+	// Device, se não for nil, é ligado à CPU antes da execução (veja
+	// IO_IN e IO_OUT).
+	Device Device
+	// Trace, se não for nil, recebe uma linha com os registradores antes
+	// de cada instrução.
+	Trace io.Writer
+}
+
+// TimeoutError indica que RunWith atingiu o tempo máximo sem HLT.
+type TimeoutError struct {
+	Timeout time.Duration
+	Steps   uint64
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("tempo limite de %v atingido após %d instruções sem HLT", e.Timeout, e.Steps)
+}
+
+// LoopError indica que a máquina voltou a um estado já visto, ou seja, que
+// o programa está em um laço infinito de Period instruções que passa por PC.
+type LoopError struct {
+	PC     uint8
+	Period uint64
+	Steps  uint64
+}
+
+func (e *LoopError) Error() string {
+	return fmt.Sprintf("laço infinito: o estado da máquina em PC=%02X se repete a cada %d instruções (detectado após %d instruções)", e.PC, e.Period, e.Steps)
+}
+
+// estado é tudo o que determina a execução a partir de um ponto.
+type estado struct {
+	ac, pc        uint8
+	n, z, v, c, b bool
+	memoria       [MEM_SIZE]uint8
+}
+
+func (c *CPU) estado() estado {
+	return estado{ac: c.AC, pc: c.PC, n: c.N, z: c.Z, v: c.V, c: c.C, b: c.B, memoria: c.Memory}
+}
+
+// RunWith executa até HLT ou até atingir um dos limites de opcoes, que é
+// devolvido como *LimitError, *TimeoutError ou *LoopError. Um erro de Step,
+// como *IOError, também interrompe a execução.
+//
+// A detecção de laços usa o algoritmo de Brent: guarda um único estado e o
+// troca pelo atual sempre que o número de passos desde a troca chega a uma
+// potência de dois. Um laço é encontrado no máximo algumas voltas depois
+// de começar, sem guardar todos os estados.
+func (c *CPU) RunWith(opcoes RunOptions) error {
+	if opcoes.Device != nil {
+		c.IO = opcoes.Device
+	}
+	inicio := time.Now()
+	var salvo estado
+	potencia, periodo := uint64(1), uint64(0)
+	if opcoes.DetectLoops {
+		salvo = c.estado()
+	}
+	transferencias := c.transferencias
+
+	for passos := uint64(0); !c.Halted; passos++ {
+		if opcoes.MaxSteps > 0 && passos >= uint64(opcoes.MaxSteps) {
+			return &LimitError{Steps: opcoes.MaxSteps}
+		}
+		// Consultar o relógio a cada instrução custaria mais que a
+		// própria instrução.
+		if opcoes.Timeout > 0 && passos%1024 == 0 && time.Since(inicio) > opcoes.Timeout {
+			return &TimeoutError{Timeout: opcoes.Timeout, Steps: passos}
+		}
+		// Como no laço original do emulador, o HLT em si não aparece no
+		// rastro: a última linha é a da instrução antes dele.
+		if opcoes.Trace != nil && c.Memory[c.PC] != HLT {
+			c.escreveRastro(opcoes.Trace)
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+
+		if opcoes.DetectLoops && !c.Halted {
+			periodo++
+			atual := c.estado()
+			if c.transferencias != transferencias {
+				transferencias = c.transferencias
+				salvo, potencia, periodo = atual, 1, 0
+			} else if atual == salvo {
+				return &LoopError{PC: c.PC, Period: periodo, Steps: passos + 1}
+			}
+			if periodo == potencia {
+				salvo = atual
+				potencia *= 2
+				periodo = 0
+			}
+		}
+	}
+	return nil
+}
+
+func (c *CPU) escreveRastro(out io.Writer) {
+	if c.Ahmes {
+		fmt.Fprintf(out, "AC: %2x PC: %2x FZ: %5t FN: %5t FV: %5t FC: %5t FB: %5t INSTRUCAO: %2x CONTEUDO: %2x\n", c.AC, c.PC, c.Z, c.N, c.V, c.C, c.B, c.Memory[c.PC], c.Memory[c.PC+1])
+	} else {
+		fmt.Fprintf(out, "AC: %2x PC: %2x FZ: %5t FN: %5t INSTRUCAO: %2x CONTEUDO: %2x\n", c.AC, c.PC, c.Z, c.N, c.Memory[c.PC], c.Memory[c.PC+1])
+	}
+}
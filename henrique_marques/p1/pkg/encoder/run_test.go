@@ -0,0 +1,101 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func carrega(t *testing.T, img []byte) *CPU {
+	t.Helper()
+	cpu := NewCPU()
+	if err := cpu.Load(img); err != nil {
+		t.Fatal(err)
+	}
+	return cpu
+}
+
+func TestRunWith(t *testing.T) {
+	// Soma 1 ao acumulador para sempre: o estado só se repete depois de
+	// 256 voltas de duas instruções.
+	contador := imagem(headerNeander, ADD, 0x10, JMP, 0x00)
+	contador[HEADER_SIZE+0x10*2] = 1
+
+	casos := []struct {
+		nome   string
+		imagem []byte
+		opcoes RunOptions
+		erro   error
+	}{
+		{nome: "sem limites", imagem: imagem(headerNeander, NOP, HLT)},
+		{nome: "HLT com detecção de laços", imagem: imagem(headerNeander, NOP, HLT), opcoes: RunOptions{DetectLoops: true}},
+		{nome: "MaxSteps", imagem: contador, opcoes: RunOptions{MaxSteps: 100}, erro: &LimitError{Steps: 100}},
+		{nome: "laço de uma instrução", imagem: imagem(headerNeander, JMP, 0x00), opcoes: RunOptions{DetectLoops: true}, erro: &LoopError{PC: 0x00, Period: 1, Steps: 1}},
+		{nome: "laço que muda o acumulador", imagem: contador, opcoes: RunOptions{DetectLoops: true}, erro: &LoopError{PC: 0x02, Period: 512, Steps: 1023}},
+		{nome: "MaxSteps antes do laço", imagem: contador, opcoes: RunOptions{DetectLoops: true, MaxSteps: 300}, erro: &LimitError{Steps: 300}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			err := carrega(t, c.imagem).RunWith(c.opcoes)
+			switch esperado := c.erro.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("erro inesperado: %v", err)
+				}
+			case *LimitError:
+				var limite *LimitError
+				if !errors.As(err, &limite) || *limite != *esperado {
+					t.Fatalf("erro %v, esperado %v", err, esperado)
+				}
+			case *LoopError:
+				var laco *LoopError
+				if !errors.As(err, &laco) || laco.PC != esperado.PC || laco.Period != esperado.Period || laco.Steps > esperado.Steps {
+					t.Fatalf("erro %v, esperado %v", err, esperado)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	cpu := carrega(t, imagem(headerNeander, JMP, 0x00))
+	err := cpu.RunWith(RunOptions{Timeout: time.Millisecond})
+	var limite *TimeoutError
+	if !errors.As(err, &limite) || limite.Timeout != time.Millisecond {
+		t.Fatalf("erro %v, esperado *TimeoutError", err)
+	}
+	if !strings.HasPrefix(err.Error(), "tempo limite de 1ms atingido") {
+		t.Errorf("mensagem: %s", err)
+	}
+}
+
+func TestRunWithTrace(t *testing.T) {
+	var rastro bytes.Buffer
+	cpu := carrega(t, imagem(headerNeander, LDA, 0x05, NOT, HLT, 0, 0x80))
+	if err := cpu.RunWith(RunOptions{Trace: &rastro}); err != nil {
+		t.Fatal(err)
+	}
+	// O HLT é executado, mas não tem linha no rastro.
+	linhas := strings.Split(strings.TrimSuffix(rastro.String(), "\n"), "\n")
+	esperadas := []string{
+		"AC:  0 PC:  0 FZ:  true FN: false INSTRUCAO: 20 CONTEUDO:  5",
+		"AC: 80 PC:  2 FZ: false FN:  true INSTRUCAO: 60 CONTEUDO: f0",
+	}
+	if strings.Join(linhas, "\n") != strings.Join(esperadas, "\n") {
+		t.Errorf("rastro:\n%s\nesperado:\n%s", rastro.String(), strings.Join(esperadas, "\n"))
+	}
+	if !cpu.Halted || cpu.Instructions != 3 {
+		t.Errorf("Halted=%v, %d instruções, esperado HLT depois de 3", cpu.Halted, cpu.Instructions)
+	}
+
+	rastro.Reset()
+	cpu = carrega(t, imagem(headerAhmes, SHL, HLT))
+	if err := cpu.RunWith(RunOptions{Trace: &rastro}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rastro.String(), "FV: false FC: false FB: false") {
+		t.Errorf("rastro do Ahmes sem V, C e B: %s", rastro.String())
+	}
+}
@@ -0,0 +1,250 @@
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"p1/pkg/assembler"
+	"p1/pkg/compiler"
+	"p1/pkg/diag"
+	"p1/pkg/encoder"
+)
+
+// limitePassos evita que um programa que nunca chega ao HLT trave a
+// verificação.
+const limitePassos = 100000
+
+var (
+	// "; expect X = 0A" ou "; expect mem[21] = FF", com valores em hexadecimal.
+	expectRegex = regexp.MustCompile(`;\s*expect\s+(\S+)\s*=\s*(\S+)\s*$`)
+	memRegex    = regexp.MustCompile(`^(?i)mem\[([0-9A-F]{1,2})\]$`)
+	// "; expect saida = 07 0C" e "; entrada 03 04", com os bytes escritos
+	// e lidos pelo dispositivo de E/S.
+	saidaRegex   = regexp.MustCompile(`;\s*expect\s+(saida)\s*=\s*(.*?)\s*$`)
+	entradaRegex = regexp.MustCompile(`;\s*entrada\s+(.*?)\s*$`)
+)
+
+// AlvoSaida é o alvo das expectativas sobre a saída do programa.
+const AlvoSaida = "saida"
+
+// Expectativa é uma anotação "; expect ALVO = VALOR" do código-fonte. O alvo
+// é um rótulo (as variáveis de um .ldh viram rótulos no assembly), um
+// endereço na forma mem[XX] ou "saida"; neste caso Saida guarda todos os
+// bytes que o programa deve escrever no dispositivo de E/S.
+type Expectativa struct {
+	Pos   diag.Pos
+	Alvo  string
+	Valor uint8
+	Saida []byte
+}
+
+// Falha é uma expectativa que não foi atendida.
+type Falha struct {
+	Expectativa
+	Endereco uint8
+	Obtido   uint8
+	// SaidaObtida é o que o programa escreveu, nas expectativas de saída.
+	SaidaObtida []byte
+}
+
+func (f Falha) String() string {
+	if f.Alvo == AlvoSaida {
+		return fmt.Sprintf("%s: expect saida = %s, obtido %s", f.Pos, hex(f.Saida), hex(f.SaidaObtida))
+	}
+	return fmt.Sprintf("%s: expect %s = %02X, obtido %02X (endereço %02X)", f.Pos, f.Alvo, f.Valor, f.Obtido, f.Endereco)
+}
+
+// hex escreve os bytes como nas anotações, ou "(nada)".
+func hex(b []byte) string {
+	if len(b) == 0 {
+		return "(nada)"
+	}
+	partes := make([]string, len(b))
+	for i, v := range b {
+		partes[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(partes, " ")
+}
+
+// Resultado é o estado final de um programa executado por Verifica.
+type Resultado struct {
+	CPU          *encoder.CPU
+	Labels       map[string]uint8
+	Expectativas []Expectativa
+	Falhas       []Falha
+	// Saida são os bytes que o programa escreveu no dispositivo de E/S.
+	Saida []byte
+}
+
+// Ok informa se todas as expectativas foram atendidas.
+func (r *Resultado) Ok() bool {
+	return len(r.Falhas) == 0
+}
+
+// Expectativas lê as anotações "; expect" do código-fonte.
+func Expectativas(fonte string) ([]Expectativa, error) {
+	var expectativas []Expectativa
+	var erros diag.Lista
+	for n, linha := range strings.Split(fonte, "\n") {
+		linha = strings.TrimRight(linha, "\r")
+		if idx := saidaRegex.FindStringSubmatchIndex(linha); idx != nil {
+			pos := diag.Pos{Linha: n + 1, Coluna: len([]rune(linha[:idx[0]])) + 1}
+			saida, err := bytesHex(pos, linha[idx[4]:idx[5]])
+			if err != nil {
+				erros = append(erros, err.(*diag.Erro))
+				continue
+			}
+			expectativas = append(expectativas, Expectativa{Pos: pos, Alvo: AlvoSaida, Saida: saida})
+			continue
+		}
+		idx := expectRegex.FindStringSubmatchIndex(linha)
+		if idx == nil {
+			continue
+		}
+		pos := diag.Pos{Linha: n + 1, Coluna: len([]rune(linha[:idx[0]])) + 1}
+		alvo, texto := linha[idx[2]:idx[3]], linha[idx[4]:idx[5]]
+		valor, err := strconv.ParseUint(texto, 16, 8)
+		if err != nil {
+			erros.Add(pos, "valor inválido na expectativa: %s", texto)
+			continue
+		}
+		expectativas = append(expectativas, Expectativa{Pos: pos, Alvo: alvo, Valor: uint8(valor)})
+	}
+	return expectativas, erros.Err()
+}
+
+// Entrada junta os bytes das anotações "; entrada" do código-fonte, que o
+// programa lê do dispositivo de E/S na ordem em que aparecem.
+func Entrada(fonte string) ([]byte, error) {
+	var entrada []byte
+	var erros diag.Lista
+	for n, linha := range strings.Split(fonte, "\n") {
+		linha = strings.TrimRight(linha, "\r")
+		idx := entradaRegex.FindStringSubmatchIndex(linha)
+		if idx == nil {
+			continue
+		}
+		pos := diag.Pos{Linha: n + 1, Coluna: len([]rune(linha[:idx[0]])) + 1}
+		b, err := bytesHex(pos, linha[idx[2]:idx[3]])
+		if err != nil {
+			erros = append(erros, err.(*diag.Erro))
+			continue
+		}
+		entrada = append(entrada, b...)
+	}
+	return entrada, erros.Err()
+}
+
+// bytesHex lê uma lista de bytes em hexadecimal separados por espaços.
+func bytesHex(pos diag.Pos, texto string) ([]byte, error) {
+	var b []byte
+	for _, campo := range strings.Fields(texto) {
+		v, err := strconv.ParseUint(campo, 16, 8)
+		if err != nil {
+			return nil, diag.Errorf(pos, "byte inválido: %s", campo)
+		}
+		b = append(b, uint8(v))
+	}
+	return b, nil
+}
+
+// Verifica compila (se o nome terminar em .ldh, com otimização se otimiza
+// for verdadeiro), monta e executa o programa no emulador e confere as
+// expectativas anotadas nele. O dispositivo de E/S do emulador recebe os
+// bytes das anotações "; entrada". Erros de compilação,
+// de montagem, de execução ou nas anotações são retornados como error; as
+// expectativas não atendidas ficam em Resultado.Falhas.
+func Verifica(nome, fonte string, otimiza bool) (*Resultado, error) {
+	expectativas, err := Expectativas(fonte)
+	if err != nil {
+		return nil, formata(nome, fonte, err)
+	}
+	if len(expectativas) == 0 {
+		return nil, fmt.Errorf("%s: nenhuma anotação \"; expect\"", nome)
+	}
+	entrada, err := Entrada(fonte)
+	if err != nil {
+		return nil, formata(nome, fonte, err)
+	}
+
+	asm := fonte
+	if strings.EqualFold(filepath.Ext(nome), ".ldh") {
+		asm, err = compiler.Compile(fonte, otimiza)
+		if err != nil {
+			return nil, formata(nome, fonte, err)
+		}
+	}
+	asmb, err := assembler.Assemble(asm, false, false)
+	if err != nil {
+		if asm != fonte {
+			return nil, formata(nome+" (assembly gerado)", asm, err)
+		}
+		return nil, formata(nome, fonte, err)
+	}
+
+	var imagem bytes.Buffer
+	if err := asmb.EscreveMEM(&imagem); err != nil {
+		return nil, err
+	}
+	cpu := encoder.NewCPU()
+	if err := cpu.Load(imagem.Bytes()); err != nil {
+		return nil, err
+	}
+	var saida bytes.Buffer
+	dispositivo := encoder.NewDevice(bytes.NewReader(entrada), &saida)
+	if err := cpu.RunWith(encoder.RunOptions{MaxSteps: limitePassos, DetectLoops: true, Device: dispositivo}); err != nil {
+		return nil, fmt.Errorf("%s: %w", nome, err)
+	}
+
+	r := &Resultado{CPU: cpu, Labels: asmb.Labels, Expectativas: expectativas, Saida: saida.Bytes()}
+	var erros diag.Lista
+	for _, e := range expectativas {
+		if e.Alvo == AlvoSaida {
+			if !bytes.Equal(r.Saida, e.Saida) {
+				r.Falhas = append(r.Falhas, Falha{Expectativa: e, SaidaObtida: r.Saida})
+			}
+			continue
+		}
+		addr, ok := endereco(e.Alvo, asmb.Labels)
+		if !ok {
+			erros.Add(e.Pos, "rótulo desconhecido na expectativa: %s", e.Alvo)
+			continue
+		}
+		if obtido := cpu.Memory[addr]; obtido != e.Valor {
+			r.Falhas = append(r.Falhas, Falha{Expectativa: e, Endereco: addr, Obtido: obtido})
+		}
+	}
+	if len(erros) > 0 {
+		return r, formata(nome, fonte, erros)
+	}
+	return r, nil
+}
+
+// VerificaArquivo lê o arquivo e chama Verifica.
+func VerificaArquivo(caminho string, otimiza bool) (*Resultado, error) {
+	fonte, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+	return Verifica(caminho, string(fonte), otimiza)
+}
+
+func endereco(alvo string, labels map[string]uint8) (uint8, bool) {
+	if m := memRegex.FindStringSubmatch(alvo); m != nil {
+		addr, _ := strconv.ParseUint(m[1], 16, 8)
+		return uint8(addr), true
+	}
+	addr, ok := labels[alvo]
+	return addr, ok
+}
+
+// formata escreve os erros com o código-fonte, no formato do GCC.
+func formata(nome, fonte string, err error) error {
+	return errors.New(strings.TrimRight(diag.Format(nome, fonte, err), "\n"))
+}
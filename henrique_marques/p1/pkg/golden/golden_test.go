@@ -0,0 +1,104 @@
+package golden
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProgramas executa cada programa de testdata e confere as anotações
+// "; expect" dele. Os programas .ldh são verificados também compilados com
+// otimização.
+func TestProgramas(t *testing.T) {
+	arquivos, err := filepath.Glob("testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arquivos) == 0 {
+		t.Fatal("nenhum programa em testdata")
+	}
+	for _, arquivo := range arquivos {
+		for _, otimiza := range []bool{false, true} {
+			nome := filepath.Base(arquivo)
+			if otimiza {
+				if filepath.Ext(arquivo) != ".ldh" {
+					continue
+				}
+				nome += " -O"
+			}
+			t.Run(nome, func(t *testing.T) {
+				r, err := VerificaArquivo(arquivo, otimiza)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range r.Falhas {
+					t.Error(f)
+				}
+			})
+		}
+	}
+}
+
+func TestFalhaDetectada(t *testing.T) {
+	fonte := `.CODE
+LDA X
+ADD X
+STA X
+HLT
+.DATA
+X DB 02
+; expect X = 05
+; expect mem[01] = 07
+`
+	r, err := Verifica("dobro.asm", fonte, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Falhas) != 1 {
+		t.Fatalf("esperada 1 falha, obtidas %d: %v", len(r.Falhas), r.Falhas)
+	}
+	if f := r.Falhas[0]; f.Alvo != "X" || f.Obtido != 0x04 || f.Pos.Linha != 8 {
+		t.Errorf("falha inesperada: %v", f)
+	}
+}
+
+func TestRotuloDesconhecido(t *testing.T) {
+	_, err := Verifica("x.asm", ".CODE\nHLT\n; expect NADA = 00\n", false)
+	if err == nil || !strings.Contains(err.Error(), "rótulo desconhecido") {
+		t.Fatalf("esperado erro de rótulo desconhecido, obtido %v", err)
+	}
+}
+
+func TestSemExpectativas(t *testing.T) {
+	if _, err := Verifica("x.asm", ".CODE\nHLT\n", false); err == nil {
+		t.Fatal("esperado erro para programa sem anotações")
+	}
+}
+
+func TestNaoCabe(t *testing.T) {
+	fonte := "PROGRAMA \"Grande\"\nINICIO\n" + strings.Repeat("X = X * Y + 1\n", 20) + "FIM\n; expect X = 00\n"
+	_, err := Verifica("grande.ldh", fonte, false)
+	if err == nil || !strings.Contains(err.Error(), "não cabe na memória") {
+		t.Fatalf("esperado erro de memória, obtido %v", err)
+	}
+}
+
+func TestSaidaErrada(t *testing.T) {
+	fonte := "PROGRAMA \"Eco\"\nINICIO\nLEIA X\nESCREVA X + 1\nFIM\n; entrada 04\n; expect saida = 04\n"
+	r, err := Verifica("eco.ldh", fonte, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Falhas) != 1 || !bytes.Equal(r.Falhas[0].SaidaObtida, []byte{0x05}) {
+		t.Fatalf("esperada falha com saída 05, obtido %v", r.Falhas)
+	}
+}
+
+func TestEntradaEsgotada(t *testing.T) {
+	fonte := "PROGRAMA \"Eco\"\nINICIO\nLEIA X\nLEIA Y\nFIM\n; entrada 04\n; expect X = 04\n"
+	_, err := Verifica("eco.ldh", fonte, false)
+	if err == nil || !strings.Contains(err.Error(), "entrada esgotada") {
+		t.Fatalf("esperado erro de entrada esgotada, obtido %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+.AHMES
+.CODE
+        LDA X
+        SUB Y           ; 05 - 07 = FE, com empréstimo
+        STA R
+        JB DESLOCA
+        HLT
+DESLOCA: LDA X
+        SHL             ; 0A
+        ROL             ; 14
+        STA S
+        HLT
+.DATA
+X DB 05
+Y DB 07
+R DB 00
+S DB 00
+; expect R = FE
+; expect S = 14
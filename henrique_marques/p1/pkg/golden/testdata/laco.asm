@@ -0,0 +1,28 @@
+; Soma os elementos de TAB usando rótulos de código e endereço automodificável.
+N EQU 04
+.CODE
+        LDA ZERO
+        STA SOMA
+LOOP:   LDA CONT
+        JZ FIM
+        LDA SOMA
+PTR:    ADD TAB
+        STA SOMA
+        LDA PTR+1
+        ADD UM
+        STA PTR+1
+        LDA CONT
+        ADD MENOS1
+        STA CONT
+        JMP LOOP
+FIM:    HLT
+
+.DATA
+ZERO DB 00
+UM DB 01
+MENOS1 DB FF
+CONT DB N
+SOMA DB 00
+TAB DB 01, 02, 03, 04
+; expect SOMA = 0A
+; expect CONT = 00
@@ -0,0 +1,29 @@
+MACRO ZERA V
+        LDA V
+VOLTA:  JZ FIM
+        ADD MENOS1
+        JMP VOLTA
+FIM:    STA V
+ENDM
+
+MACRO SOMA X, Y, R
+        LDA X
+        ADD Y
+        STA R
+ENDM
+
+.CODE
+        SOMA A1, A2, RES
+        ZERA A1
+        ZERA A2
+        HLT
+.DATA
+MENOS1 DB FF
+A1 DB 05
+A2 DB 03
+RES DS 1
+MSG DB "Oi", 00
+; expect A1 = 00
+; expect A2 = 00
+; expect RES = 08
+; expect MSG = 4F
@@ -0,0 +1,147 @@
+package simbolos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarcaLinha inicia o comentário que o compilador põe antes do código de
+// cada instrução do .ldh, como "; @linha 5". O assembler usa as marcas para
+// ligar cada endereço à linha do programa original. Uma marca com linha 0
+// encerra o trecho da marca anterior (código sem linha correspondente).
+const MarcaLinha = "; @linha "
+
+// Tipos de símbolo.
+const (
+	TipoCodigo = "codigo"
+	TipoDado   = "dado"
+)
+
+// Tabela é o conteúdo do arquivo de símbolos (.sym) gerado pelo assembler.
+type Tabela struct {
+	// Fonte é o arquivo .asm montado e Origem, quando o assembly veio do
+	// compilador, o programa .ldh.
+	Fonte  string `json:"fonte,omitempty"`
+	Origem string `json:"origem,omitempty"`
+
+	Simbolos   []Simbolo        `json:"simbolos"`
+	Constantes map[string]uint8 `json:"constantes,omitempty"`
+	Linhas     []Linha          `json:"linhas"`
+}
+
+// Simbolo é um rótulo e o endereço que ele representa. Descricao é o
+// comentário da linha que o define; nos temporários gerados pelo compilador,
+// a subexpressão que eles guardam.
+type Simbolo struct {
+	Nome      string `json:"nome"`
+	Endereco  uint8  `json:"endereco"`
+	Tipo      string `json:"tipo"`
+	Linha     int    `json:"linha"`
+	Descricao string `json:"descricao,omitempty"`
+}
+
+// Linha liga um endereço de memória à linha do .asm que o gerou e, se
+// houver, à linha do .ldh (Origem).
+type Linha struct {
+	Endereco uint8 `json:"endereco"`
+	Linha    int   `json:"linha"`
+	Origem   int   `json:"origem,omitempty"`
+}
+
+// Marca devolve o comentário que associa o código seguinte à linha do .ldh.
+func Marca(linha int) string {
+	return MarcaLinha + strconv.Itoa(linha)
+}
+
+// LinhaDaMarca informa se a linha do .asm é uma marca e, se for, a linha do
+// .ldh que ela indica.
+func LinhaDaMarca(linha string) (int, bool) {
+	resto, ok := strings.CutPrefix(strings.TrimSpace(linha), MarcaLinha)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(resto))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// Dados devolve os símbolos de dados, ordenados por endereço e nome.
+func (t *Tabela) Dados() []Simbolo {
+	var dados []Simbolo
+	for _, s := range t.Simbolos {
+		if s.Tipo == TipoDado {
+			dados = append(dados, s)
+		}
+	}
+	sort.SliceStable(dados, func(i, j int) bool {
+		if dados[i].Endereco != dados[j].Endereco {
+			return dados[i].Endereco < dados[j].Endereco
+		}
+		return dados[i].Nome < dados[j].Nome
+	})
+	return dados
+}
+
+// Labels devolve o endereço de cada símbolo, no formato usado pelo
+// depurador.
+func (t *Tabela) Labels() map[string]uint8 {
+	labels := make(map[string]uint8, len(t.Simbolos))
+	for _, s := range t.Simbolos {
+		labels[s.Nome] = s.Endereco
+	}
+	return labels
+}
+
+// Escreve grava a tabela em JSON.
+func (t *Tabela) Escreve(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// Grava escreve a tabela no arquivo .sym.
+func (t *Tabela) Grava(caminho string) error {
+	arquivo, err := os.Create(caminho)
+	if err != nil {
+		return err
+	}
+	if err := t.Escreve(arquivo); err != nil {
+		arquivo.Close()
+		return err
+	}
+	return arquivo.Close()
+}
+
+// Le carrega um arquivo .sym.
+func Le(caminho string) (*Tabela, error) {
+	dados, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, err
+	}
+	var t Tabela
+	if err := json.Unmarshal(dados, &t); err != nil {
+		return nil, fmt.Errorf("arquivo de símbolos inválido %s: %w", caminho, err)
+	}
+	return &t, nil
+}
+
+// Procura carrega o arquivo .sym com o mesmo nome da imagem (prog.mem ->
+// prog.sym). Se ele não existir, devolve nil sem erro.
+func Procura(caminhoImagem string) (*Tabela, error) {
+	caminho := strings.TrimSuffix(caminhoImagem, filepath.Ext(caminhoImagem)) + ".sym"
+	t, err := Le(caminho)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return t, err
+}